@@ -15,6 +15,12 @@ const (
 	ArgoCDConfigMapName     = "argocd-cm"
 	ArgoCDSecretName        = "argocd-secret"
 	ArgoCDRBACConfigMapName = "argocd-rbac-cm"
+	// ArgoCDTLSCertsConfigMapName holds additional trusted CA certificates in PEM format, keyed by hostname
+	ArgoCDTLSCertsConfigMapName = "argocd-tls-certs-cm"
+	// ArgoCDKnownHostsConfigMapName holds the SSH known hosts used for Git-over-SSH repositories
+	ArgoCDKnownHostsConfigMapName = "argocd-ssh-known-hosts-cm"
+	// ArgoCDResourceActionsConfigMapName holds resource actions, keyed by <group>_<kind> or <kind>
+	ArgoCDResourceActionsConfigMapName = "argocd-resource-actions-cm"
 )
 
 // Default system namespace
@@ -62,12 +62,17 @@ func (c *clusterInfo) replaceResourceCache(gk schema.GroupKind, resourceVersion
 	if ok {
 		objByKind := make(map[kube.ResourceKey]*unstructured.Unstructured)
 		for i := range objs {
-			objByKind[kube.GetResourceKey(&objs[i])] = &objs[i]
+			if !c.isExcludedObject(&objs[i]) {
+				objByKind[kube.GetResourceKey(&objs[i])] = &objs[i]
+			}
 		}
 
 		for i := range objs {
 			obj := &objs[i]
 			key := kube.GetResourceKey(&objs[i])
+			if _, ok := objByKind[key]; !ok {
+				continue
+			}
 			existingNode, exists := c.nodes[key]
 			c.onNodeUpdated(exists, existingNode, obj, key)
 		}
@@ -85,6 +90,17 @@ func (c *clusterInfo) replaceResourceCache(gk schema.GroupKind, resourceVersion
 	}
 }
 
+// isExcludedObject returns whether the object is excluded by the resource inclusions and exclusions which are
+// restricted to resource names
+func (c *clusterInfo) isExcludedObject(un *unstructured.Unstructured) bool {
+	resourcesFilter := c.cacheSettingsSrc().ResourcesFilter
+	if resourcesFilter == nil {
+		return false
+	}
+	gvk := un.GroupVersionKind()
+	return resourcesFilter.IsExcludedNamedResource(gvk.Group, gvk.Kind, c.cluster.Server, un.GetName())
+}
+
 func (c *clusterInfo) createObjInfo(un *unstructured.Unstructured, appInstanceLabel string) *node {
 	ownerRefs := un.GetOwnerReferences()
 	// Special case for endpoint. Remove after https://github.com/kubernetes/kubernetes/issues/28483 is fixed
@@ -295,6 +311,9 @@ func (c *clusterInfo) sync() (err error) {
 
 		lock.Lock()
 		for i := range list.Items {
+			if c.isExcludedObject(&list.Items[i]) {
+				continue
+			}
 			c.setNode(c.createObjInfo(&list.Items[i], c.cacheSettingsSrc().AppInstanceLabelKey))
 		}
 		lock.Unlock()
@@ -449,7 +468,8 @@ func (c *clusterInfo) processEvent(event watch.EventType, un *unstructured.Unstr
 	defer c.lock.Unlock()
 	key := kube.GetResourceKey(un)
 	existingNode, exists := c.nodes[key]
-	if event == watch.Deleted {
+	// excluded objects are never cached, so events about them only remove stale nodes
+	if event == watch.Deleted || c.isExcludedObject(un) {
 		if exists {
 			c.onNodeRemoved(key, existingNode)
 		}
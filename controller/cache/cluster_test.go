@@ -23,6 +23,7 @@ import (
 	appv1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/argo-cd/util/kube"
 	"github.com/argoproj/argo-cd/util/kube/kubetest"
+	"github.com/argoproj/argo-cd/util/settings"
 )
 
 func strToUnstructured(jsonStr string) *unstructured.Unstructured {
@@ -438,6 +439,40 @@ func TestWatchCacheUpdated(t *testing.T) {
 	assert.True(t, ok)
 }
 
+func TestExcludedNamedResources(t *testing.T) {
+	excluded := testPod.DeepCopy()
+	excluded.SetName("excluded-pod")
+	excluded.SetUID("4")
+
+	cluster := newCluster(testPod, excluded)
+	cluster.cacheSettingsSrc = func() *cacheSettings {
+		return &cacheSettings{
+			AppInstanceLabelKey: common.LabelKeyAppInstance,
+			ResourcesFilter: &settings.ResourcesFilter{
+				ResourceExclusions: []settings.FilteredResource{{APIGroups: []string{""}, Kinds: []string{"Pod"}, Name: "excluded-*"}},
+			},
+		}
+	}
+	err := cluster.ensureSynced()
+	assert.Nil(t, err)
+
+	_, ok := cluster.nodes[kube.GetResourceKey(testPod)]
+	assert.True(t, ok)
+	_, ok = cluster.nodes[kube.GetResourceKey(excluded)]
+	assert.False(t, ok)
+
+	err = cluster.processEvent(watch.Modified, excluded)
+	assert.Nil(t, err)
+	_, ok = cluster.nodes[kube.GetResourceKey(excluded)]
+	assert.False(t, ok)
+
+	cluster.replaceResourceCache(testPod.GroupVersionKind().GroupKind(), "updated-list-version", []unstructured.Unstructured{*testPod, *excluded})
+	_, ok = cluster.nodes[kube.GetResourceKey(testPod)]
+	assert.True(t, ok)
+	_, ok = cluster.nodes[kube.GetResourceKey(excluded)]
+	assert.False(t, ok)
+}
+
 func TestGetDuplicatedChildren(t *testing.T) {
 	extensionsRS := testRS.DeepCopy()
 	extensionsRS.SetGroupVersionKind(schema.GroupVersionKind{Group: "extensions", Kind: kube.ReplicaSetKind, Version: "v1beta1"})
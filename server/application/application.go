@@ -125,6 +125,13 @@ func (s *Server) Create(ctx context.Context, q *application.ApplicationCreateReq
 	defer s.projectLock.Unlock(q.Application.Spec.Project)
 
 	a := q.Application
+	if a.Spec.Destination.Namespace == "" {
+		defaultNamespace, err := s.settingsMgr.GetDefaultDestinationNamespace()
+		if err != nil {
+			return nil, err
+		}
+		a.Spec.Destination.Namespace = defaultNamespace
+	}
 	err := s.validateAndNormalizeApp(ctx, &a)
 	if err != nil {
 		return nil, err
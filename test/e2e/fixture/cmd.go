@@ -9,6 +9,28 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// transientErrors are output fragments of commands which failed due to a temporary API server problem
+var transientErrors = []string{
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"etcdserver: request timed out",
+	"the server is currently unable to handle the request",
+	"the server was unable to return a response in the time allotted",
+	"Internal error occurred",
+	"Too many requests",
+}
+
+var (
+	// runner runs commands for RunWithRetry, it is a variable so it can be replaced in tests
+	runner = Run
+	// sleep waits between retries of RunWithRetry, it is a variable so it can be replaced in tests
+	sleep = time.Sleep
+	// initialRetryBackoff is the delay before the first retry of RunWithRetry, doubled for each following retry
+	initialRetryBackoff = 1 * time.Second
+)
+
 func Run(workDir, name string, args ...string) (string, error) {
 
 	start := time.Now()
@@ -36,3 +58,40 @@ func Run(workDir, name string, args ...string) (string, error) {
 
 	return output, err
 }
+
+// RunWithRetry runs the command given by args (command name followed by its arguments) up to the given number of
+// attempts, backing off exponentially between attempts. Only transient failures are retried. A failed attempt may
+// still have been applied by the API server, so an AlreadyExists error of a retry is treated as success.
+func RunWithRetry(workDir string, attempts int, args ...string) (string, error) {
+	backoff := initialRetryBackoff
+	var output string
+	var err error
+	for attempt := 1; ; attempt++ {
+		output, err = runner(workDir, args[0], args[1:]...)
+		if err != nil && attempt > 1 && isAlreadyExistsError(output, err) {
+			log.WithFields(log.Fields{"args": args, "attempt": attempt}).Info("resource created by a previous attempt")
+			return output, nil
+		}
+		if err == nil || attempt >= attempts || !isTransientError(output, err) {
+			return output, err
+		}
+		log.WithFields(log.Fields{"args": args, "attempt": attempt, "backoff": backoff}).Warn("transient error, retrying")
+		sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isAlreadyExistsError returns whether the command output or error indicate the resource to create already exists
+func isAlreadyExistsError(output string, err error) bool {
+	return strings.Contains(output, "AlreadyExists") || strings.Contains(err.Error(), "AlreadyExists")
+}
+
+// isTransientError returns whether the command output or error indicate a temporary failure
+func isTransientError(output string, err error) bool {
+	for _, transientError := range transientErrors {
+		if strings.Contains(output, transientError) || strings.Contains(err.Error(), transientError) {
+			return true
+		}
+	}
+	return false
+}
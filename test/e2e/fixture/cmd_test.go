@@ -0,0 +1,86 @@
+package fixture
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withFakeRunner(outputs []string, errs []error) (calls *int, sleeps *[]time.Duration, restore func()) {
+	calls = new(int)
+	sleeps = &[]time.Duration{}
+	origRunner, origSleep := runner, sleep
+	runner = func(workDir, name string, args ...string) (string, error) {
+		i := *calls
+		*calls++
+		return outputs[i], errs[i]
+	}
+	sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+	}
+	return calls, sleeps, func() {
+		runner, sleep = origRunner, origSleep
+	}
+}
+
+func TestRunWithRetry_TransientErrorRetried(t *testing.T) {
+	calls, sleeps, restore := withFakeRunner(
+		[]string{"Unable to connect to the server: dial tcp: i/o timeout", "etcdserver: request timed out", "ok"},
+		[]error{errors.New("exit status 1"), errors.New("exit status 1"), nil})
+	defer restore()
+
+	output, err := RunWithRetry("", 5, "kubectl", "create", "ns", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", output)
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []time.Duration{initialRetryBackoff, 2 * initialRetryBackoff}, *sleeps)
+}
+
+func TestRunWithRetry_NonTransientErrorNotRetried(t *testing.T) {
+	calls, sleeps, restore := withFakeRunner(
+		[]string{"error: unknown flag: --foo"},
+		[]error{errors.New("exit status 1")})
+	defer restore()
+
+	_, err := RunWithRetry("", 5, "kubectl", "create", "ns", "--foo")
+	assert.Error(t, err)
+	assert.Equal(t, 1, *calls)
+	assert.Empty(t, *sleeps)
+}
+
+func TestRunWithRetry_AttemptsExhausted(t *testing.T) {
+	calls, sleeps, restore := withFakeRunner(
+		[]string{"connection refused", "connection refused"},
+		[]error{errors.New("exit status 1"), errors.New("exit status 1")})
+	defer restore()
+
+	_, err := RunWithRetry("", 2, "kubectl", "get", "ns")
+	assert.Error(t, err)
+	assert.Equal(t, 2, *calls)
+	assert.Len(t, *sleeps, 1)
+}
+
+func TestRunWithRetry_AlreadyExistsAfterRetry(t *testing.T) {
+	calls, _, restore := withFakeRunner(
+		[]string{"etcdserver: request timed out", `Error from server (AlreadyExists): namespaces "foo" already exists`},
+		[]error{errors.New("exit status 1"), errors.New("exit status 1")})
+	defer restore()
+
+	_, err := RunWithRetry("", 5, "kubectl", "create", "ns", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestRunWithRetry_AlreadyExistsOnFirstAttempt(t *testing.T) {
+	calls, sleeps, restore := withFakeRunner(
+		[]string{`Error from server (AlreadyExists): namespaces "foo" already exists`},
+		[]error{errors.New("exit status 1")})
+	defer restore()
+
+	_, err := RunWithRetry("", 5, "kubectl", "create", "ns", "foo")
+	assert.Error(t, err)
+	assert.Equal(t, 1, *calls)
+	assert.Empty(t, *sleeps)
+}
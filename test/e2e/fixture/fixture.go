@@ -34,10 +34,19 @@ import (
 )
 
 const (
-	defaultAriServer = "localhost:8080"
-	adminPassword    = "password"
-	testingLabel     = "e2e.argoproj.io"
-	ArgoCDNamespace  = "argocd-e2e"
+	defaultAriServer     = "localhost:8080"
+	defaultAdminPassword = "password"
+	testingLabel         = "e2e.argoproj.io"
+	ArgoCDNamespace      = "argocd-e2e"
+
+	// resyncAnnotation is set on the settings config map by SubscribeAndWait to force a settings notification
+	resyncAnnotation = testingLabel + "/resync"
+
+	// retryAttempts is the number of attempts for kubectl commands which may fail due to transient errors
+	retryAttempts = 5
+
+	// envAdminPassword is the environment variable which overrides the admin password used to log in
+	envAdminPassword = "ARGOCD_E2E_ADMIN_PASSWORD"
 
 	// ensure all repos are in one directory tree, so we can easily clean them up
 	tmpDir = "/tmp/argo-e2e"
@@ -54,6 +63,11 @@ var (
 	token            string
 	plainText        bool
 	repoUrl          string
+	// originalSSOSettings holds the SSO related settings in place before a test changed them
+	originalSSOSettings *settings.ArgoCDSettings
+	// originalConfigMapValues holds the values of the settings config map keys changed by SetConfigMapKey before
+	// they were first changed, nil if a key was absent
+	originalConfigMapValues = make(map[string]*string)
 )
 
 // getKubeConfig creates new kubernetes client config using specified config path and config overrides variables
@@ -75,9 +89,22 @@ func init() {
 	AppClientset = appclientset.NewForConfigOrDie(config)
 	KubeClientset = kubernetes.NewForConfigOrDie(config)
 	apiServerAddress = os.Getenv(argocdclient.EnvArgoCDServer)
+	apiServerAddressSource := argocdclient.EnvArgoCDServer
 	if apiServerAddress == "" {
 		apiServerAddress = defaultAriServer
+		apiServerAddressSource = "default"
 	}
+	adminPassword := os.Getenv(envAdminPassword)
+	adminPasswordSource := envAdminPassword
+	if adminPassword == "" {
+		adminPassword = defaultAdminPassword
+		adminPasswordSource = "default"
+	}
+	log.WithFields(log.Fields{
+		"apiServerAddress":       apiServerAddress,
+		"apiServerAddressSource": apiServerAddressSource,
+		"adminPasswordSource":    adminPasswordSource,
+	}).Info("using api server")
 	tlsTestResult, err := grpcutil.TestTLS(apiServerAddress)
 	CheckError(err)
 
@@ -110,6 +137,10 @@ func Name() string {
 	return name
 }
 
+func SettingsManager() *settings.SettingsManager {
+	return settingsManager
+}
+
 func repoDirectory() string {
 	return path.Join(tmpDir, name)
 }
@@ -125,14 +156,22 @@ func DeploymentNamespace() string {
 	return dnsFriendly(fmt.Sprintf("argocd-e2e-%s", id))
 }
 
-// creates a secret for the current test, this currently can only create a single secret
+// creates a secret for the current test, use CreateSecretWithSuffix to create more than one secret per test
 func CreateSecret(username, password string) string {
+	return CreateSecretWithSuffix("", username, password)
+}
+
+// creates a secret for the current test whose name ends with the given suffix, so multiple secrets can coexist
+func CreateSecretWithSuffix(suffix, username, password string) string {
 	secretName := fmt.Sprintf("argocd-e2e-%s", name)
-	FailOnErr(Run("", "kubectl", "create", "secret", "generic", secretName,
+	if suffix != "" {
+		secretName = fmt.Sprintf("%s-%s", secretName, strings.ToLower(suffix))
+	}
+	FailOnErr(RunWithRetry("", retryAttempts, "kubectl", "create", "secret", "generic", secretName,
 		"--from-literal=username="+username,
 		"--from-literal=password="+password,
 		"-n", ArgoCDNamespace))
-	FailOnErr(Run("", "kubectl", "label", "secret", secretName, testingLabel+"=true", "-n", ArgoCDNamespace))
+	FailOnErr(RunWithRetry("", retryAttempts, "kubectl", "label", "secret", secretName, testingLabel+"=true", "-n", ArgoCDNamespace))
 	return secretName
 }
 
@@ -143,15 +182,178 @@ func Settings(consumer func(s *settings.ArgoCDSettings)) {
 	CheckError(settingsManager.SaveSettings(s))
 }
 
+// saveOriginalSSOSettings records the SSO related settings so that EnsureCleanState can restore them
+func saveOriginalSSOSettings(s *settings.ArgoCDSettings) {
+	if originalSSOSettings == nil {
+		originalSSOSettings = &settings.ArgoCDSettings{URL: s.URL, DexConfig: s.DexConfig, OIDCConfigRAW: s.OIDCConfigRAW}
+	}
+}
+
+// ensureURL sets the external URL required by SSO, if it is not set already
+func ensureURL(s *settings.ArgoCDSettings) {
+	if s.URL == "" {
+		scheme := "https"
+		if plainText {
+			scheme = "http"
+		}
+		s.URL = fmt.Sprintf("%s://%s", scheme, apiServerAddress)
+	}
+}
+
+// SetOIDCConfig configures the given OIDC settings; EnsureCleanState restores the original SSO settings
+func SetOIDCConfig(cfg settings.OIDCConfig) {
+	yamlBytes, err := yaml.Marshal(cfg)
+	CheckError(err)
+	Settings(func(s *settings.ArgoCDSettings) {
+		saveOriginalSSOSettings(s)
+		ensureURL(s)
+		s.OIDCConfigRAW = string(yamlBytes)
+	})
+}
+
+// SetDexConfig configures the given dex config; EnsureCleanState restores the original SSO settings
+func SetDexConfig(dexConfig string) {
+	Settings(func(s *settings.ArgoCDSettings) {
+		saveOriginalSSOSettings(s)
+		ensureURL(s)
+		s.DexConfig = dexConfig
+	})
+}
+
+// WaitForSettings polls the settings until the given predicate holds or the timeout expires
+func WaitForSettings(predicate func(s *settings.ArgoCDSettings) bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := settingsManager.ResyncInformers()
+		if err != nil {
+			return err
+		}
+		s, err := settingsManager.GetSettings()
+		if err != nil {
+			return err
+		}
+		if predicate(s) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v waiting for settings", timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// GetResyncedSettings returns the settings as currently seen through the settings manager after forcing a resync of
+// its informers, so ConfigMap changes made by a test are reflected without waiting for the next informer event
+func GetResyncedSettings() (*settings.ArgoCDSettings, error) {
+	err := settingsManager.ResyncInformers()
+	if err != nil {
+		return nil, err
+	}
+	return settingsManager.GetSettings()
+}
+
+// SubscribeAndWait subscribes to settings updates, triggers a resync of the settings manager and returns the settings
+// of the first notification. The settings config map is temporarily annotated after the resync, so subscribers are
+// notified even if the settings didn't change since the last notification.
+func SubscribeAndWait(timeout time.Duration) (s *settings.ArgoCDSettings, err error) {
+	subCh := settingsManager.SubscribeBuffered(1)
+	defer settingsManager.UnsubscribeBuffered(subCh)
+
+	err = settingsManager.ResyncInformers()
+	if err != nil {
+		return nil, err
+	}
+	err = tryUpdateSettingConfigMap(func(cm *corev1.ConfigMap) error {
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string)
+		}
+		cm.Annotations[resyncAnnotation] = time.Now().Format(time.RFC3339Nano)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		cleanupErr := tryUpdateSettingConfigMap(func(cm *corev1.ConfigMap) error {
+			delete(cm.Annotations, resyncAnnotation)
+			return nil
+		})
+		if cleanupErr != nil && err == nil {
+			s, err = nil, cleanupErr
+		}
+	}()
+
+	select {
+	case s = <-subCh:
+		return s, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("timed out after %v waiting for settings notification", timeout)
+	}
+}
+
+// SetAppInstanceLabelKey sets the label key used to track application resources; EnsureCleanState removes it
+func SetAppInstanceLabelKey(key string) {
+	updateSettingConfigMap(func(cm *corev1.ConfigMap) error {
+		if key != "" {
+			cm.Data["application.instanceLabelKey"] = key
+		} else {
+			delete(cm.Data, "application.instanceLabelKey")
+		}
+		return nil
+	})
+}
+
 func updateSettingConfigMap(updater func(cm *corev1.ConfigMap) error) {
+	errors.CheckError(tryUpdateSettingConfigMap(updater))
+}
+
+// tryUpdateSettingConfigMap works like updateSettingConfigMap, but returns errors instead of failing
+func tryUpdateSettingConfigMap(updater func(cm *corev1.ConfigMap) error) error {
 	cm, err := KubeClientset.CoreV1().ConfigMaps(ArgoCDNamespace).Get(common.ArgoCDConfigMapName, v1.GetOptions{})
-	errors.CheckError(err)
+	if err != nil {
+		return err
+	}
 	if cm.Data == nil {
 		cm.Data = make(map[string]string)
 	}
-	errors.CheckError(updater(cm))
+	if err = updater(cm); err != nil {
+		return err
+	}
 	_, err = KubeClientset.CoreV1().ConfigMaps(ArgoCDNamespace).Update(cm)
-	errors.CheckError(err)
+	return err
+}
+
+// SetConfigMapKey sets an arbitrary key of the settings config map; EnsureCleanState restores its original value
+func SetConfigMapKey(key, value string) {
+	updateSettingConfigMap(func(cm *corev1.ConfigMap) error {
+		if _, ok := originalConfigMapValues[key]; !ok {
+			if original, exists := cm.Data[key]; exists {
+				originalConfigMapValues[key] = &original
+			} else {
+				originalConfigMapValues[key] = nil
+			}
+		}
+		cm.Data[key] = value
+		return nil
+	})
+}
+
+// resetConfigMapKeys restores the settings config map keys changed by SetConfigMapKey
+func resetConfigMapKeys() {
+	if len(originalConfigMapValues) == 0 {
+		return
+	}
+	updateSettingConfigMap(func(cm *corev1.ConfigMap) error {
+		for key, original := range originalConfigMapValues {
+			if original != nil {
+				cm.Data[key] = *original
+			} else {
+				delete(cm.Data, key)
+			}
+		}
+		return nil
+	})
+	originalConfigMapValues = make(map[string]*string)
 }
 
 func SetResourceOverrides(overrides map[string]v1alpha1.ResourceOverride) {
@@ -186,6 +388,18 @@ func SetHelmRepoCredential(creds settings.HelmRepoCredentials) {
 	})
 }
 
+func SetRepos(repos ...settings.RepoCredentials) {
+	Settings(func(s *settings.ArgoCDSettings) {
+		s.Repositories = repos
+	})
+}
+
+func SetRepoCredentials(creds ...settings.RepoCredentials) {
+	Settings(func(s *settings.ArgoCDSettings) {
+		s.RepositoryCredentials = creds
+	})
+}
+
 func EnsureCleanState(t *testing.T) {
 
 	start := time.Now()
@@ -201,27 +415,40 @@ func EnsureCleanState(t *testing.T) {
 	CheckError(KubeClientset.CoreV1().Secrets(ArgoCDNamespace).DeleteCollection(
 		&v1.DeleteOptions{PropagationPolicy: &policy}, v1.ListOptions{LabelSelector: testingLabel + "=true"}))
 
-	FailOnErr(Run("", "kubectl", "delete", "ns", "-l", testingLabel+"=true", "--field-selector", "status.phase=Active", "--wait=false"))
+	FailOnErr(RunWithRetry("", retryAttempts, "kubectl", "delete", "ns", "-l", testingLabel+"=true", "--field-selector", "status.phase=Active", "--wait=false"))
 
 	// reset settings
 	s, err := settingsManager.GetSettings()
 	CheckError(err)
+	if originalSSOSettings != nil {
+		s.URL = originalSSOSettings.URL
+		s.DexConfig = originalSSOSettings.DexConfig
+		s.OIDCConfigRAW = originalSSOSettings.OIDCConfigRAW
+		originalSSOSettings = nil
+	}
 	CheckError(settingsManager.SaveSettings(&settings.ArgoCDSettings{
 		// changing theses causes a restart
-		AdminPasswordHash:    s.AdminPasswordHash,
-		AdminPasswordMtime:   s.AdminPasswordMtime,
-		ServerSignature:      s.ServerSignature,
-		Certificate:          s.Certificate,
-		DexConfig:            s.DexConfig,
-		OIDCConfigRAW:        s.OIDCConfigRAW,
-		URL:                  s.URL,
-		WebhookGitHubSecret:  s.WebhookGitHubSecret,
-		WebhookGitLabSecret:  s.WebhookGitLabSecret,
-		WebhookBitbucketUUID: s.WebhookBitbucketUUID,
-		Secrets:              s.Secrets,
+		AdminPasswordHash:            s.AdminPasswordHash,
+		AdminPasswordMtime:           s.AdminPasswordMtime,
+		AdminDisabled:                s.AdminDisabled,
+		ServerSignature:              s.ServerSignature,
+		PreviousServerSignature:      s.PreviousServerSignature,
+		ServerSignatureRotationTime:  s.ServerSignatureRotationTime,
+		Certificate:                  s.Certificate,
+		Insecure:                     s.Insecure,
+		DexConfig:                    s.DexConfig,
+		OIDCConfigRAW:                s.OIDCConfigRAW,
+		URL:                          s.URL,
+		WebhookGitHubSecret:          s.WebhookGitHubSecret,
+		WebhookGitLabSecret:          s.WebhookGitLabSecret,
+		WebhookBitbucketUUID:         s.WebhookBitbucketUUID,
+		WebhookBitbucketServerSecret: s.WebhookBitbucketServerSecret,
+		Secrets:                      s.Secrets,
 	}))
 	SetResourceOverrides(make(map[string]v1alpha1.ResourceOverride))
 	SetConfigManagementPlugins()
+	SetAppInstanceLabelKey("")
+	resetConfigMapKeys()
 
 	// remove tmp dir
 	CheckError(os.RemoveAll(tmpDir))
@@ -243,8 +470,8 @@ func EnsureCleanState(t *testing.T) {
 	FailOnErr(Run(repoDirectory(), "git", "commit", "-q", "-m", "initial commit"))
 
 	// create namespace
-	FailOnErr(Run("", "kubectl", "create", "ns", DeploymentNamespace()))
-	FailOnErr(Run("", "kubectl", "label", "ns", DeploymentNamespace(), testingLabel+"=true"))
+	FailOnErr(RunWithRetry("", retryAttempts, "kubectl", "create", "ns", DeploymentNamespace()))
+	FailOnErr(RunWithRetry("", retryAttempts, "kubectl", "label", "ns", DeploymentNamespace(), testingLabel+"=true"))
 
 	log.WithFields(log.Fields{"duration": time.Since(start), "name": name, "id": id}).Info("clean state")
 }
@@ -326,3 +553,8 @@ func Declarative(filename string, values interface{}) (string, error) {
 
 	return Run("", "kubectl", "-n", ArgoCDNamespace, "apply", "-f", tmpFile.Name())
 }
+
+// create the application using the typed application clientset, and return the created application
+func DeclarativeApp(app v1alpha1.Application) (*v1alpha1.Application, error) {
+	return AppClientset.ArgoprojV1alpha1().Applications(ArgoCDNamespace).Create(&app)
+}
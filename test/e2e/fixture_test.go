@@ -0,0 +1,186 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/argoproj/argo-cd/common"
+	"github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/test/e2e/fixture"
+	"github.com/argoproj/argo-cd/util/settings"
+)
+
+// make sure multiple labeled secrets can be created in one test and are removed by the clean-up
+func TestCreateMultipleSecrets(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	secretNames := []string{
+		fixture.CreateSecret("repo-user", "repo-password"),
+		fixture.CreateSecretWithSuffix("cluster", "cluster-user", "cluster-password"),
+	}
+	assert.NotEqual(t, secretNames[0], secretNames[1])
+	for _, secretName := range secretNames {
+		secret, err := fixture.KubeClientset.CoreV1().Secrets(fixture.ArgoCDNamespace).Get(secretName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "true", secret.Labels["e2e.argoproj.io"])
+	}
+
+	fixture.EnsureCleanState(t)
+
+	for _, secretName := range secretNames {
+		_, err := fixture.KubeClientset.CoreV1().Secrets(fixture.ArgoCDNamespace).Get(secretName, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	}
+}
+
+// make sure repositories registered by the fixture are visible to the settings manager and removed by the clean-up
+func TestSetRepos(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	fixture.SetRepos(settings.RepoCredentials{URL: fixture.RepoURL()})
+	fixture.SetRepoCredentials(settings.RepoCredentials{URL: "https://github.com/argoproj"})
+
+	s, err := fixture.SettingsManager().GetSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, []settings.RepoCredentials{{URL: fixture.RepoURL()}}, s.Repositories)
+	assert.Equal(t, []settings.RepoCredentials{{URL: "https://github.com/argoproj"}}, s.RepositoryCredentials)
+
+	fixture.EnsureCleanState(t)
+
+	s, err = fixture.SettingsManager().GetSettings()
+	assert.NoError(t, err)
+	assert.Empty(t, s.Repositories)
+	assert.Empty(t, s.RepositoryCredentials)
+}
+
+// make sure tests can wait until a resource override set by the fixture is visible
+func TestWaitForSettings(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	fixture.SetResourceOverrides(map[string]v1alpha1.ResourceOverride{"apps/Deployment": {HealthLua: "return {}"}})
+
+	err := fixture.WaitForSettings(func(_ *settings.ArgoCDSettings) bool {
+		overrides, err := fixture.SettingsManager().GetResourceOverrides()
+		return err == nil && overrides["apps/Deployment"].HealthLua == "return {}"
+	}, 30*time.Second)
+	assert.NoError(t, err)
+}
+
+// make sure a changed app instance label key is visible to the settings manager and removed by the clean-up
+func TestGetResyncedSettings(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	fixture.SetAppInstanceLabelKey("e2e.argoproj.io/instance")
+
+	s, err := fixture.GetResyncedSettings()
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+	labelKey, err := fixture.SettingsManager().GetAppInstanceLabelKey()
+	assert.NoError(t, err)
+	assert.Equal(t, "e2e.argoproj.io/instance", labelKey)
+
+	fixture.EnsureCleanState(t)
+
+	_, err = fixture.GetResyncedSettings()
+	assert.NoError(t, err)
+	labelKey, err = fixture.SettingsManager().GetAppInstanceLabelKey()
+	assert.NoError(t, err)
+	assert.Equal(t, common.LabelKeyAppInstance, labelKey)
+}
+
+// make sure an arbitrary config map key set by the fixture is visible to the settings manager and removed by the clean-up
+func TestSetConfigMapKey(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	fixture.SetConfigMapKey("timeout.reconciliation", "60s")
+
+	s, err := fixture.GetResyncedSettings()
+	assert.NoError(t, err)
+	assert.Equal(t, 60*time.Second, s.ReconciliationTimeout())
+
+	fixture.EnsureCleanState(t)
+
+	cm, err := fixture.KubeClientset.CoreV1().ConfigMaps(fixture.ArgoCDNamespace).Get(common.ArgoCDConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, cm.Data, "timeout.reconciliation")
+}
+
+// make sure a config map key overwritten by the fixture is restored by the clean-up, while other keys are kept
+func TestSetConfigMapKey_RestoreOverwrittenKey(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	configMaps := fixture.KubeClientset.CoreV1().ConfigMaps(fixture.ArgoCDNamespace)
+	cm, err := configMaps.Get(common.ArgoCDConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data["e2e.original"] = "original"
+	_, err = configMaps.Update(cm)
+	assert.NoError(t, err)
+
+	fixture.SetConfigMapKey("e2e.original", "changed")
+	cm, err = configMaps.Get(common.ArgoCDConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	cm.Data["e2e.unrelated"] = "unrelated"
+	_, err = configMaps.Update(cm)
+	assert.NoError(t, err)
+
+	fixture.EnsureCleanState(t)
+
+	cm, err = configMaps.Get(common.ArgoCDConfigMapName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "original", cm.Data["e2e.original"])
+	assert.Equal(t, "unrelated", cm.Data["e2e.unrelated"])
+
+	delete(cm.Data, "e2e.original")
+	delete(cm.Data, "e2e.unrelated")
+	_, err = configMaps.Update(cm)
+	assert.NoError(t, err)
+}
+
+// make sure subscribers are notified about the settings after a resource override was set by the fixture
+func TestSubscribeAndWait(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	fixture.SetResourceOverrides(map[string]v1alpha1.ResourceOverride{"apps/Deployment": {HealthLua: "return {}"}})
+
+	s, err := fixture.SubscribeAndWait(30 * time.Second)
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+
+	overrides, err := fixture.SettingsManager().GetResourceOverrides()
+	assert.NoError(t, err)
+	assert.Equal(t, "return {}", overrides["apps/Deployment"].HealthLua)
+}
+
+// make sure an application created from a typed object can be fetched back
+func TestDeclarativeApp(t *testing.T) {
+	fixture.EnsureCleanState(t)
+
+	created, err := fixture.DeclarativeApp(v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: fixture.Name()},
+		Spec: v1alpha1.ApplicationSpec{
+			Project: common.DefaultAppProjectName,
+			Source: v1alpha1.ApplicationSource{
+				RepoURL: fixture.RepoURL(),
+				Path:    "guestbook",
+			},
+			Destination: v1alpha1.ApplicationDestination{
+				Server:    common.KubernetesInternalAPIServerAddr,
+				Namespace: fixture.DeploymentNamespace(),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	app, err := fixture.AppClientset.ArgoprojV1alpha1().Applications(fixture.ArgoCDNamespace).Get(fixture.Name(), metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, created.UID, app.UID)
+	assert.Equal(t, fixture.RepoURL(), app.Spec.Source.RepoURL)
+	assert.Equal(t, "guestbook", app.Spec.Source.Path)
+}
@@ -28,6 +28,10 @@ func GenerateDexConfigYAML(settings *settings.ArgoCDSettings) ([]byte, error) {
 	dexCfg["grpc"] = map[string]interface{}{
 		"addr": "0.0.0.0:5557",
 	}
+	dexClientSecret, err := settings.DexOAuth2ClientSecret()
+	if err != nil {
+		return nil, err
+	}
 	dexCfg["oauth2"] = map[string]interface{}{
 		"skipApprovalScreen": true,
 	}
@@ -35,7 +39,7 @@ func GenerateDexConfigYAML(settings *settings.ArgoCDSettings) ([]byte, error) {
 		{
 			"id":     common.ArgoCDClientAppID,
 			"name":   common.ArgoCDClientAppName,
-			"secret": settings.DexOAuth2ClientSecret(),
+			"secret": dexClientSecret,
 			"redirectURIs": []string{
 				settings.RedirectURL(),
 			},
@@ -95,11 +95,11 @@ func (mgr *SessionManager) Create(subject string, secondsBeforeExpiry int64) (st
 
 func (mgr *SessionManager) signClaims(claims jwt.Claims) (string, error) {
 	log.Infof("Issuing claims: %v", claims)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	settings, err := mgr.settingsMgr.GetSettings()
 	if err != nil {
 		return "", err
 	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(settings.JWTSigningAlgorithm()), claims)
 	return token.SignedString(settings.ServerSignature)
 }
 
@@ -114,13 +114,21 @@ func (mgr *SessionManager) Parse(tokenString string) (jwt.Claims, error) {
 	if err != nil {
 		return nil, err
 	}
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		// Don't forget to validate the alg is what you expect:
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	// Tokens signed with the previous signature are accepted during the signature rotation grace period
+	var token *jwt.Token
+	for _, signature := range settings.ServerSignatures() {
+		claims = jwt.MapClaims{}
+		token, err = jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+			// Don't forget to validate the alg is what you expect:
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+			}
+			return signature, nil
+		})
+		if !isSignatureInvalid(err) {
+			break
 		}
-		return settings.ServerSignature, nil
-	})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +140,13 @@ func (mgr *SessionManager) Parse(tokenString string) (jwt.Claims, error) {
 	return token.Claims, nil
 }
 
+// isSignatureInvalid returns whether the token failed to parse only because of a signature mismatch, so it may
+// verify with an older signature
+func isSignatureInvalid(err error) bool {
+	validationErr, ok := err.(*jwt.ValidationError)
+	return ok && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0
+}
+
 // VerifyUsernamePassword verifies if a username/password combo is correct
 func (mgr *SessionManager) VerifyUsernamePassword(username, password string) error {
 	if username != common.ArgoCDAdminUsername {
@@ -3,6 +3,7 @@ package session_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	corev1 "k8s.io/api/core/v1"
@@ -58,3 +59,89 @@ func TestSessionManager(t *testing.T) {
 		t.Errorf("Token claim subject \"%s\" does not match expected subject \"%s\".", subject, defaultSubject)
 	}
 }
+
+func TestSessionManager_SignatureRotation(t *testing.T) {
+	bcrypt, err := password.HashPassword("password")
+	errors.CheckError(err)
+	kubeclientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd-cm",
+			Namespace: "argocd",
+		},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd-secret",
+			Namespace: "argocd",
+		},
+		Data: map[string][]byte{
+			"admin.password":   []byte(bcrypt),
+			"server.secretkey": []byte("Hello, world!"),
+		},
+	})
+
+	settingsMgr := settings.NewSettingsManager(context.Background(), kubeclientset, "argocd")
+	mgr := sessionutil.NewSessionManager(settingsMgr, "")
+
+	oldToken, err := mgr.Create("argo", 0)
+	errors.CheckError(err)
+
+	errors.CheckError(settingsMgr.RotateServerSignature())
+
+	newToken, err := mgr.Create("argo", 0)
+	errors.CheckError(err)
+
+	if _, err := mgr.Parse(oldToken); err != nil {
+		t.Errorf("Could not parse token signed with previous signature: %v", err)
+	}
+	if _, err := mgr.Parse(newToken); err != nil {
+		t.Errorf("Could not parse token signed with new signature: %v", err)
+	}
+}
+
+func TestSessionManager_ExpiredTokenAfterRotation(t *testing.T) {
+	bcrypt, err := password.HashPassword("password")
+	errors.CheckError(err)
+	kubeclientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd-cm",
+			Namespace: "argocd",
+		},
+	}, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "argocd-secret",
+			Namespace: "argocd",
+		},
+		Data: map[string][]byte{
+			"admin.password":   []byte(bcrypt),
+			"server.secretkey": []byte("Hello, world!"),
+		},
+	})
+
+	settingsMgr := settings.NewSettingsManager(context.Background(), kubeclientset, "argocd")
+	mgr := sessionutil.NewSessionManager(settingsMgr, "")
+	errors.CheckError(settingsMgr.RotateServerSignature())
+	argoSettings, err := settingsMgr.GetSettings()
+	errors.CheckError(err)
+
+	// an expired token signed with the current signature reports the expiry rather than an invalid signature
+	now := time.Now()
+	expiredToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+		IssuedAt:  now.Add(-2 * time.Hour).Unix(),
+		ExpiresAt: now.Add(-time.Hour).Unix(),
+		Issuer:    sessionutil.SessionManagerClaimsIssuer,
+		Subject:   "argo",
+	}).SignedString(argoSettings.ServerSignature)
+	errors.CheckError(err)
+
+	_, err = mgr.Parse(expiredToken)
+	validationErr, ok := err.(*jwt.ValidationError)
+	if !ok {
+		t.Fatalf("Expected a validation error, got: %v", err)
+	}
+	if validationErr.Errors&jwt.ValidationErrorExpired == 0 {
+		t.Errorf("Expected an expiry error, got: %v", err)
+	}
+	if validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+		t.Errorf("Expected a valid signature, got: %v", err)
+	}
+}
@@ -9,6 +9,8 @@ type FilteredResource struct {
 	APIGroups []string `json:"apiGroups,omitempty"`
 	Kinds     []string `json:"kinds,omitempty"`
 	Clusters  []string `json:"clusters,omitempty"`
+	// Name is an optional glob pattern restricting the filter to resources with matching names
+	Name string `json:"name,omitempty"`
 }
 
 func (r FilteredResource) matchGroup(apiGroup string) bool {
@@ -47,6 +49,15 @@ func (r FilteredResource) matchCluster(cluster string) bool {
 	return len(r.Clusters) == 0
 }
 
+func (r FilteredResource) matchName(name string) bool {
+	return r.Name == "" || match(r.Name, name)
+}
+
 func (r FilteredResource) Match(apiGroup, kind, cluster string) bool {
 	return r.matchGroup(apiGroup) && r.matchKind(kind) && r.matchCluster(cluster)
 }
+
+// MatchResource works like Match, but additionally matches the name of an individual resource
+func (r FilteredResource) MatchResource(apiGroup, kind, cluster, name string) bool {
+	return r.Match(apiGroup, kind, cluster) && r.matchName(name)
+}
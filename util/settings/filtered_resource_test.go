@@ -37,3 +37,17 @@ func TestExcludeResource(t *testing.T) {
 	assert.False(t, FilteredResource{APIGroups: []string{""}, Kinds: []string{"["}, Clusters: []string{""}}.Match("", "", ""))
 	assert.False(t, FilteredResource{APIGroups: []string{""}, Kinds: []string{""}, Clusters: []string{"["}}.Match("", "", ""))
 }
+
+func TestMatchResourceName(t *testing.T) {
+	filter := FilteredResource{APIGroups: []string{""}, Kinds: []string{"ConfigMap"}, Name: "*-ca-bundle"}
+	assert.True(t, filter.MatchResource("", "ConfigMap", "in-cluster", "kube-root-ca-bundle"))
+	assert.False(t, filter.MatchResource("", "ConfigMap", "in-cluster", "argocd-cm"))
+	assert.False(t, filter.MatchResource("", "Secret", "in-cluster", "kube-root-ca-bundle"))
+	assert.False(t, filter.MatchResource("apps", "ConfigMap", "in-cluster", "kube-root-ca-bundle"))
+
+	// an empty name matches any name
+	assert.True(t, FilteredResource{Kinds: []string{"ConfigMap"}}.MatchResource("", "ConfigMap", "in-cluster", "argocd-cm"))
+
+	// rubbish pattern
+	assert.False(t, FilteredResource{Name: "["}.MatchResource("", "ConfigMap", "in-cluster", "argocd-cm"))
+}
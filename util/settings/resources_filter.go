@@ -31,7 +31,14 @@ func (rf *ResourcesFilter) isIncludedResource(apiGroup, kind, cluster string) bo
 }
 
 func (rf *ResourcesFilter) isExcludedResource(apiGroup, kind, cluster string) bool {
-	return rf.checkResourcePresence(apiGroup, kind, cluster, rf.getExcludedResources())
+	// exclusions restricted to resource names exclude individual resources, but not the whole kind
+	var kindExclusions []FilteredResource
+	for _, excludedResource := range rf.getExcludedResources() {
+		if excludedResource.Name == "" {
+			kindExclusions = append(kindExclusions, excludedResource)
+		}
+	}
+	return rf.checkResourcePresence(apiGroup, kind, cluster, kindExclusions)
 }
 
 // Behavior of this function is as follows:
@@ -68,3 +75,29 @@ func (rf *ResourcesFilter) IsExcludedResource(apiGroup, kind, cluster string) bo
 		return rf.isExcludedResource(apiGroup, kind, cluster)
 	}
 }
+
+// IsExcludedNamedResource works like IsExcludedResource, but also applies the inclusions and exclusions which are
+// restricted to resource names to the individual resource with the given name
+func (rf *ResourcesFilter) IsExcludedNamedResource(apiGroup, kind, cluster, name string) bool {
+	if rf.IsExcludedResource(apiGroup, kind, cluster) {
+		return true
+	}
+	if len(rf.ResourceInclusions) > 0 {
+		included := false
+		for _, includedResource := range rf.ResourceInclusions {
+			if includedResource.MatchResource(apiGroup, kind, cluster, name) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return true
+		}
+	}
+	for _, excludedResource := range rf.ResourceExclusions {
+		if excludedResource.Name != "" && excludedResource.MatchResource(apiGroup, kind, cluster, name) {
+			return true
+		}
+	}
+	return false
+}
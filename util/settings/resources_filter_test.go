@@ -49,3 +49,15 @@ func TestResourceInclusionsExclusionNonMutex(t *testing.T) {
 	assert.True(t, filter.IsExcludedResource("not-whitelisted-resource", "whitelisted-kind", ""))
 	assert.True(t, filter.IsExcludedResource("not-whitelisted-resource", "", ""))
 }
+
+func TestResourceExclusionsByName(t *testing.T) {
+	filter := ResourcesFilter{
+		ResourceExclusions: []FilteredResource{{APIGroups: []string{""}, Kinds: []string{"ConfigMap"}, Name: "*-ca-bundle"}},
+	}
+
+	// the kind is still watched
+	assert.False(t, filter.IsExcludedResource("", "ConfigMap", ""))
+	assert.True(t, filter.IsExcludedNamedResource("", "ConfigMap", "", "kube-root-ca-bundle"))
+	assert.False(t, filter.IsExcludedNamedResource("", "ConfigMap", "", "argocd-cm"))
+	assert.False(t, filter.IsExcludedNamedResource("", "Secret", "", "kube-root-ca-bundle"))
+}
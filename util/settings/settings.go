@@ -1,26 +1,43 @@
 package settings
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ghodss/yaml"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
 	apiv1 "k8s.io/api/core/v1"
 	apierr "k8s.io/apimachinery/pkg/api/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	v1 "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	v1listers "k8s.io/client-go/listers/core/v1"
@@ -47,6 +64,11 @@ type ArgoCDSettings struct {
 	OIDCConfigRAW string `json:"oidcConfig,omitempty"`
 	// ServerSignature holds the key used to generate JWT tokens.
 	ServerSignature []byte `json:"serverSignature,omitempty"`
+	// PreviousServerSignature holds the key replaced by the last signature rotation. Tokens signed
+	// with it are still accepted during the rotation grace period.
+	PreviousServerSignature []byte `json:"previousServerSignature,omitempty"`
+	// ServerSignatureRotationTime holds the time of the last signature rotation, which starts the grace period
+	ServerSignatureRotationTime time.Time `json:"serverSignatureRotationTime,omitempty"`
 	// Certificate holds the certificate/private key for the Argo CD API server.
 	// If nil, will run insecure without TLS.
 	Certificate *tls.Certificate `json:"-"`
@@ -56,23 +78,158 @@ type ArgoCDSettings struct {
 	WebhookGitLabSecret string `json:"webhookGitLabSecret,omitempty"`
 	// WebhookBitbucketUUID holds the UUID for authenticating Bitbucket webhook events
 	WebhookBitbucketUUID string `json:"webhookBitbucketUUID,omitempty"`
+	// WebhookBitbucketServerSecret holds the shared secret for authenticating Bitbucket Server webhook events
+	WebhookBitbucketServerSecret string `json:"webhookBitbucketServerSecret,omitempty"`
 	// Secrets holds all secrets in argocd-secret as a map[string]string
 	Secrets map[string]string `json:"secrets,omitempty"`
+	// externalSecrets holds the values of $secretName:key references to other secrets, keyed by reference. It is
+	// populated by the settings manager, which has access to the secrets of the namespace.
+	externalSecrets map[string]string
 	// Repositories holds list of configured git repositories
 	Repositories []RepoCredentials
 	// Repositories holds list of repo credentials
 	RepositoryCredentials []RepoCredentials
 	// Repositories holds list of configured helm repositories
 	HelmRepositories []HelmRepoCredentials
+	// AdminDisabled indicates that the built-in admin account is disabled
+	AdminDisabled bool `json:"adminDisabled,omitempty"`
+	// Insecure indicates that the API server intentionally runs without TLS
+	Insecure bool `json:"insecure,omitempty"`
+	// OIDCCacheExpirationRAW holds the expiration of cached OIDC discovery documents as a raw duration string
+	OIDCCacheExpirationRAW string `json:"oidcCacheExpiration,omitempty"`
+	// StylesURL holds the URL of a custom stylesheet loaded by the UI
+	StylesURL string `json:"stylesURL,omitempty"`
+	// StylesContent holds custom CSS applied by the UI
+	StylesContent string `json:"stylesContent,omitempty"`
+	// HelmDefaultValueFilesRAW holds the YAML list of value files applied to all Helm applications
+	HelmDefaultValueFilesRAW string `json:"helmDefaultValueFiles,omitempty"`
+	// HelmValueFileSchemesRAW holds the YAML list of URL schemes Helm value files may use
+	HelmValueFileSchemesRAW string `json:"helmValueFileSchemes,omitempty"`
+	// ApplicationNamespacesRAW holds the comma-separated or YAML list of namespace patterns applications may use
+	ApplicationNamespacesRAW string `json:"applicationNamespaces,omitempty"`
+	// JWTSigningAlgorithmRAW holds the HMAC algorithm used to sign JWTs with the server signature
+	JWTSigningAlgorithmRAW string `json:"jwtSigningAlgorithm,omitempty"`
+	// AnonymousUserEnabledRAW holds the flag which grants the default role to unauthenticated requests
+	AnonymousUserEnabledRAW string `json:"anonymousUserEnabled,omitempty"`
+	// GitRequestTimeoutRAW holds the duration after which Git requests to repositories time out
+	GitRequestTimeoutRAW string `json:"gitRequestTimeout,omitempty"`
+	// ReconciliationTimeoutRAW holds the interval at which applications are reconciled
+	ReconciliationTimeoutRAW string `json:"reconciliationTimeout,omitempty"`
+	// GitSubmoduleEnabledRAW holds the flag enabling the checkout of Git submodules
+	GitSubmoduleEnabledRAW string `json:"gitSubmoduleEnabled,omitempty"`
+	// RespectRBACRAW holds the mode in which the controller respects the RBAC of managed clusters
+	RespectRBACRAW string `json:"respectRBAC,omitempty"`
+	// BasePathRAW holds the path prefix under which a reverse proxy serves Argo CD
+	BasePathRAW string `json:"basePath,omitempty"`
+	// ExtraHTTPHeadersRAW holds the YAML map of headers added to every HTTP response of the API server
+	ExtraHTTPHeadersRAW string `json:"extraHTTPHeaders,omitempty"`
+	// ControllerReplicasRAW holds the number of application controller shards
+	ControllerReplicasRAW string `json:"controllerReplicas,omitempty"`
+	// MaxConcurrentSyncsRAW holds the maximum number of sync operations running at the same time
+	MaxConcurrentSyncsRAW string `json:"maxConcurrentSyncs,omitempty"`
 }
 
 type OIDCConfig struct {
-	Name            string   `json:"name,omitempty"`
-	Issuer          string   `json:"issuer,omitempty"`
-	ClientID        string   `json:"clientID,omitempty"`
-	ClientSecret    string   `json:"clientSecret,omitempty"`
-	CLIClientID     string   `json:"cliClientID,omitempty"`
-	RequestedScopes []string `json:"requestedScopes,omitempty"`
+	Name                   string                `json:"name,omitempty"`
+	Issuer                 string                `json:"issuer,omitempty"`
+	IssuerAlias            string                `json:"issuerAlias,omitempty"`
+	ClientID               string                `json:"clientID,omitempty"`
+	ClientSecret           string                `json:"clientSecret,omitempty"`
+	CLIClientID            string                `json:"cliClientID,omitempty"`
+	RequestedScopes        []string              `json:"requestedScopes,omitempty"`
+	CLIRequestedScopes     []string              `json:"cliRequestedScopes,omitempty"`
+	RequestedIDTokenClaims map[string]*OIDCClaim `json:"requestedIDTokenClaims,omitempty"`
+	EnablePKCE             bool                  `json:"enablePKCE,omitempty"`
+	AllowedAudiences       []string              `json:"allowedAudiences,omitempty"`
+	Prompt                 string                `json:"prompt,omitempty"`
+	MaxAge                 *int                  `json:"maxAge,omitempty"`
+	// RootCASkipVerify disables TLS certificate verification of the OIDC provider. Never use it in production.
+	RootCASkipVerify bool `json:"rootCASkipVerify,omitempty"`
+}
+
+// AuthRequestParameters returns the additional parameters of the authorization request: prompt and max_age.
+// Parameters which are not configured are omitted.
+func (c *OIDCConfig) AuthRequestParameters() map[string]string {
+	params := make(map[string]string)
+	if c == nil {
+		return params
+	}
+	if c.Prompt != "" {
+		params["prompt"] = c.Prompt
+	}
+	if c.MaxAge != nil {
+		params["max_age"] = strconv.Itoa(*c.MaxAge)
+	}
+	return params
+}
+
+// Issuers returns the issuer values accepted when verifying ID tokens: the issuer and, if set, the issuer alias
+func (c *OIDCConfig) Issuers() []string {
+	issuers := []string{c.Issuer}
+	if c.IssuerAlias != "" && c.IssuerAlias != c.Issuer {
+		issuers = append(issuers, c.IssuerAlias)
+	}
+	return issuers
+}
+
+// GetAllowedAudiences returns the audiences accepted when verifying ID tokens. Defaults to the client ID and,
+// if set, the CLI client ID.
+func (c *OIDCConfig) GetAllowedAudiences() []string {
+	if len(c.AllowedAudiences) > 0 {
+		return c.AllowedAudiences
+	}
+	audiences := []string{c.ClientID}
+	if c.CLIClientID != "" {
+		audiences = append(audiences, c.CLIClientID)
+	}
+	return audiences
+}
+
+// ScopesFor returns the scopes to request for the CLI or the web UI. The CLI uses CLIRequestedScopes when set
+// and falls back to RequestedScopes otherwise.
+func (c *OIDCConfig) ScopesFor(cli bool) []string {
+	if cli && len(c.CLIRequestedScopes) > 0 {
+		return c.CLIRequestedScopes
+	}
+	return c.RequestedScopes
+}
+
+// ScopeString returns the space-separated scope parameter of the authorization request. The openid scope is always
+// requested first and duplicate scopes are removed.
+func (c *OIDCConfig) ScopeString() string {
+	scopes := []string{"openid"}
+	if c != nil {
+		for _, scope := range c.RequestedScopes {
+			if scope != "" && !containsString(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// IsPKCEEnabled returns whether the authorization code flow should use PKCE (code verifier and challenge)
+func (c *OIDCConfig) IsPKCEEnabled() bool {
+	return c != nil && c.EnablePKCE
+}
+
+// OIDCClaim describes the requirements on an individual claim requested via the OIDC claims request parameter
+type OIDCClaim struct {
+	Essential bool     `json:"essential,omitempty"`
+	Values    []string `json:"values,omitempty"`
+}
+
+// IDTokenClaimsRequestParameter returns the JSON value of the claims authorization request parameter which requests
+// the configured ID token claims, or an empty string if no claims are configured
+func (c *OIDCConfig) IDTokenClaimsRequestParameter() (string, error) {
+	if len(c.RequestedIDTokenClaims) == 0 {
+		return "", nil
+	}
+	claims, err := json.Marshal(map[string]map[string]*OIDCClaim{"id_token": c.RequestedIDTokenClaims})
+	if err != nil {
+		return "", err
+	}
+	return string(claims), nil
 }
 
 type RepoCredentials struct {
@@ -81,6 +238,71 @@ type RepoCredentials struct {
 	PasswordSecret        *apiv1.SecretKeySelector `json:"passwordSecret,omitempty"`
 	SSHPrivateKeySecret   *apiv1.SecretKeySelector `json:"sshPrivateKeySecret,omitempty"`
 	InsecureIgnoreHostKey bool                     `json:"insecureIgnoreHostKey,omitempty"`
+	Insecure              bool                     `json:"insecure,omitempty"`
+	WebhookSecret         *apiv1.SecretKeySelector `json:"webhookSecret,omitempty"`
+	RequireSignature      bool                     `json:"requireSignature,omitempty"`
+
+	// urlTemplate and usernameSecretNameTemplate hold the values before ${ENV:VAR} substitution, so that
+	// SaveSettings persists the templates rather than the substituted values
+	urlTemplate                string
+	usernameSecretNameTemplate string
+}
+
+// envVarRefRegex matches ${ENV:VAR} references
+var envVarRefRegex = regexp.MustCompile(`\$\{ENV:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// repoEnvVarPrefix is the prefix of the environment variables which may be referenced by repository credentials
+const repoEnvVarPrefix = "ARGOCD_REPO_"
+
+// substituteEnvVars replaces ${ENV:VAR} references by the values of the referenced environment variables. Only
+// variables prefixed with ARGOCD_REPO_ may be referenced, other and undefined variables are left intact.
+func substituteEnvVars(value string) string {
+	return envVarRefRegex.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envVarRefRegex.FindStringSubmatch(ref)[1]
+		if !strings.HasPrefix(name, repoEnvVarPrefix) {
+			log.Warnf("environment variable '%s' referenced by '%s' is not allowed, only %s* variables may be referenced", name, value, repoEnvVarPrefix)
+			return ref
+		}
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			log.Warnf("environment variable '%s' referenced by '%s' is not defined", name, value)
+			return ref
+		}
+		return envValue
+	})
+}
+
+// substituteRepoEnvVars resolves ${ENV:VAR} references in the URL and username secret name of the given credentials
+func substituteRepoEnvVars(creds []RepoCredentials) {
+	for i := range creds {
+		if url := substituteEnvVars(creds[i].URL); url != creds[i].URL {
+			creds[i].urlTemplate = creds[i].URL
+			creds[i].URL = url
+		}
+		if creds[i].UsernameSecret != nil {
+			if name := substituteEnvVars(creds[i].UsernameSecret.Name); name != creds[i].UsernameSecret.Name {
+				creds[i].usernameSecretNameTemplate = creds[i].UsernameSecret.Name
+				creds[i].UsernameSecret.Name = name
+			}
+		}
+	}
+}
+
+// repoCredentialsTemplates returns copies of the given credentials with substituted values replaced by their templates
+func repoCredentialsTemplates(creds []RepoCredentials) []RepoCredentials {
+	templates := make([]RepoCredentials, len(creds))
+	for i, cred := range creds {
+		if cred.urlTemplate != "" && substituteEnvVars(cred.urlTemplate) == cred.URL {
+			cred.URL = cred.urlTemplate
+		}
+		if cred.UsernameSecret != nil && cred.usernameSecretNameTemplate != "" && substituteEnvVars(cred.usernameSecretNameTemplate) == cred.UsernameSecret.Name {
+			usernameSecret := cred.UsernameSecret.DeepCopy()
+			usernameSecret.Name = cred.usernameSecretNameTemplate
+			cred.UsernameSecret = usernameSecret
+		}
+		templates[i] = cred
+	}
+	return templates
 }
 
 type HelmRepoCredentials struct {
@@ -91,6 +313,7 @@ type HelmRepoCredentials struct {
 	CASecret       *apiv1.SecretKeySelector `json:"caSecret,omitempty"`
 	CertSecret     *apiv1.SecretKeySelector `json:"certSecret,omitempty"`
 	KeySecret      *apiv1.SecretKeySelector `json:"keySecret,omitempty"`
+	EnableOCI      bool                     `json:"enableOCI,omitempty"`
 }
 
 const (
@@ -100,6 +323,10 @@ const (
 	settingAdminPasswordMtimeKey = "admin.passwordMtime"
 	// settingServerSignatureKey designates the key for a server secret key inside a Kubernetes secret.
 	settingServerSignatureKey = "server.secretkey"
+	// settingServerSignaturePreviousKey designates the key for the previous server secret key inside a Kubernetes secret.
+	settingServerSignaturePreviousKey = "server.secretkey.previous"
+	// settingServerSignatureRotationTimeKey designates the key for the time of the last server secret key rotation inside a Kubernetes secret.
+	settingServerSignatureRotationTimeKey = "server.secretkey.rotationTime"
 	// settingServerCertificate designates the key for the public cert used in TLS
 	settingServerCertificate = "tls.crt"
 	// settingServerPrivateKey designates the key for the private key used in TLS
@@ -122,6 +349,10 @@ const (
 	settingsWebhookGitLabSecretKey = "webhook.gitlab.secret"
 	// settingsWebhookBitbucketUUID is the key for Bitbucket webhook UUID
 	settingsWebhookBitbucketUUIDKey = "webhook.bitbucket.uuid"
+	// settingsWebhookBitbucketServerSecretKey is the key for the Bitbucket Server shared webhook secret
+	settingsWebhookBitbucketServerSecretKey = "webhook.bitbucketserver.secret"
+	// webhookPreviousSecretKeySuffix is the suffix of the keys holding the webhook secrets replaced by a rotation
+	webhookPreviousSecretKeySuffix = ".previous"
 	// settingsApplicationInstanceLabelKey is the key to configure injected app instance label key
 	settingsApplicationInstanceLabelKey = "application.instanceLabelKey"
 	// resourcesCustomizationsKey is the key to the map of resource overrides
@@ -132,8 +363,128 @@ const (
 	resourceInclusionsKey = "resource.inclusions"
 	// configManagementPluginsKey is the key to the list of config management plugins
 	configManagementPluginsKey = "configManagementPlugins"
+	// settingAdminEnabledKey designates the key which enables or disables the built-in admin account
+	settingAdminEnabledKey = "admin.enabled"
+	// settingsOIDCCacheExpirationKey designates the key for the expiration of cached OIDC discovery documents
+	settingsOIDCCacheExpirationKey = "oidc.cacheExpiration"
+	// settingsInstallationIDKey designates the key of the unique installation identifier
+	settingsInstallationIDKey = "installationID"
+	// settingUICSSURLKey designates the key for the URL of a custom UI stylesheet
+	settingUICSSURLKey = "ui.cssURL"
+	// settingUICSSContentKey designates the key for custom UI CSS
+	settingUICSSContentKey = "ui.cssContent"
+	// helmDefaultValueFilesKey designates the key for the list of value files applied to all Helm applications
+	helmDefaultValueFilesKey = "helm.defaultValueFiles"
+	// helmValueFileSchemesKey designates the key for the list of URL schemes Helm value files may use
+	helmValueFileSchemesKey = "helm.valueFileSchemes"
+	// applicationNamespacesKey designates the key for the list of namespace patterns applications may use
+	applicationNamespacesKey = "application.namespaces"
+	// sshKnownHostsKey designates the key of the known_hosts data in the ArgoCDKnownHostsConfigMap
+	sshKnownHostsKey = "ssh_known_hosts"
+	// resourceCompareOptionsKey designates the key for the resource comparison options
+	resourceCompareOptionsKey = "resource.compareoptions"
+	// settingJWTSigningAlgorithmKey designates the key for the algorithm used to sign JWTs with the server signature
+	settingJWTSigningAlgorithmKey = "server.jwtSigningAlgorithm"
+	// settingServerInsecureKey designates the key for the flag indicating that the API server runs without TLS
+	settingServerInsecureKey = "server.insecure"
+	// settingAnonymousUserEnabledKey designates the key for the flag enabling anonymous access
+	settingAnonymousUserEnabledKey = "users.anonymous.enabled"
+	// accountsKeyPrefix is the prefix of the keys configuring local accounts and their capabilities
+	accountsKeyPrefix = "accounts"
+	// accountEnabledKeySuffix is the suffix of the keys enabling or disabling local accounts
+	accountEnabledKeySuffix = "enabled"
+	// accountTokensKeySuffix is the suffix of the argocd-secret keys holding the API key tokens of local accounts
+	accountTokensKeySuffix = "tokens"
+	// defaultProjectKey designates the key for the project of new applications which don't specify one
+	defaultProjectKey = "application.defaultProject"
+	// defaultSourceReposKey designates the key for the list of source repositories new applications may use by default
+	defaultSourceReposKey = "application.defaultSourceRepos"
+	// defaultDestinationsKey designates the key for the list of destinations new applications may use by default
+	defaultDestinationsKey = "application.defaultDestinations"
+	// gitRequestTimeoutKey designates the key for the duration after which Git requests time out
+	gitRequestTimeoutKey = "timeout.gitRequest"
+	// reconciliationTimeoutKey designates the key for the interval at which applications are reconciled
+	reconciliationTimeoutKey = "timeout.reconciliation"
+	// dexConfigConnectorKeyPrefix is the prefix of the keys holding dex connectors merged into the dex config
+	dexConfigConnectorKeyPrefix = "dex.config.connector."
+	// gitSubmoduleEnabledKey designates the key for the flag enabling the checkout of Git submodules
+	gitSubmoduleEnabledKey = "reposerver.git.submodule.enabled"
+	// featuresKey designates the key for the map of experimental feature flags
+	featuresKey = "features"
+	// respectRBACKey designates the key for the mode in which the controller respects the RBAC of managed clusters
+	respectRBACKey = "resource.respectRBAC"
+	// defaultDestinationNamespaceKey designates the key for the destination namespace of applications which don't specify one
+	defaultDestinationNamespaceKey = "application.defaultDestinationNamespace"
+	// settingServerRootPathKey designates the key for the path prefix under which a reverse proxy serves Argo CD
+	settingServerRootPathKey = "server.rootpath"
+	// settingServerBaseHRefKey designates the alternative key for the path prefix, server.rootpath takes precedence
+	settingServerBaseHRefKey = "server.basehref"
+	// settingServerExtraHeadersKey designates the key for the headers added to every HTTP response of the API server
+	settingServerExtraHeadersKey = "server.extraHeaders"
+	// controllerReplicasKey designates the key for the number of application controller shards
+	controllerReplicasKey = "controller.replicas"
+	// maxConcurrentSyncsKey designates the key for the maximum number of sync operations running at the same time
+	maxConcurrentSyncsKey = "application.sync.maxConcurrent"
 )
 
+// defaultHelmValueFileSchemes are the URL schemes Helm value files may use if not configured
+var defaultHelmValueFileSchemes = []string{"https", "http"}
+
+// cacheSyncTimeout is the time an attempt to sync the informer caches may take
+var cacheSyncTimeout = time.Minute
+
+// cacheSyncBackoff controls the retries of failed attempts to sync the informer caches
+var cacheSyncBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    5,
+}
+
+// installationIDAttempts is the number of attempts to persist a new installation ID before giving up
+const installationIDAttempts = 5
+
+// Structured log fields attached to settings warnings
+const (
+	logFieldSettingKey = "settingKey"
+	logFieldNamespace  = "namespace"
+	logFieldSecret     = "secret"
+)
+
+// defaultJWTSigningAlgorithm is the JWT signing algorithm used if server.jwtSigningAlgorithm is not configured
+const defaultJWTSigningAlgorithm = "HS256"
+
+// defaultGitRequestTimeout is the Git request timeout used if timeout.gitRequest is not configured
+const defaultGitRequestTimeout = 15 * time.Second
+
+// defaultReconciliationTimeout is the reconciliation timeout used if timeout.reconciliation is not configured
+const defaultReconciliationTimeout = 180 * time.Second
+
+// Modes in which the controller respects the RBAC of managed clusters when listing resources
+const (
+	// RespectRBACStrict verifies the permissions of each resource kind
+	RespectRBACStrict = "strict"
+	// RespectRBACNormal skips resource kinds which can't be listed
+	RespectRBACNormal = "normal"
+)
+
+// jwtSigningAlgorithms are the allowed JWT signing algorithms
+var jwtSigningAlgorithms = []string{"HS256", "HS384", "HS512"}
+
+// saveSettingsAttempts is the number of attempts to update the settings ConfigMap or Secret on conflicts
+const saveSettingsAttempts = 5
+
+// cliLoopbackRedirectURL is the URL the CLI receives the SSO callback on, using the default --sso-port
+const cliLoopbackRedirectURL = "http://localhost:8085" + common.CallbackEndpoint
+
+// defaultOIDCCacheExpiration is the expiration of cached OIDC discovery documents if not configured
+const defaultOIDCCacheExpiration = 10 * time.Minute
+
+// serverSignatureGracePeriod is how long tokens signed with the previous server signature are accepted after a rotation
+const serverSignatureGracePeriod = 24 * time.Hour
+
+// redactedValue replaces secret values which must not be exposed
+const redactedValue = "******"
+
 // SettingsManager holds config info for a new manager with which to access Kubernetes ConfigMaps.
 type SettingsManager struct {
 	ctx        context.Context
@@ -141,13 +492,116 @@ type SettingsManager struct {
 	secrets    v1listers.SecretLister
 	configmaps v1listers.ConfigMapLister
 	namespace  string
+	// configMapName and secretName are the names of the ArgoCDConfigMap and the ArgoCDSecret
+	configMapName string
+	secretName    string
+	// fallbackNamespace is an optional namespace whose settings provide keys missing in the primary namespace
+	fallbackNamespace  string
+	fallbackSecrets    v1listers.SecretLister
+	fallbackConfigmaps v1listers.ConfigMapLister
 	// subscribers is a list of subscribers to settings updates
 	subscribers []chan<- *ArgoCDSettings
+	// filteredSubscribers is a list of subscribers which are notified only about changes of specific settings sections
+	filteredSubscribers []filteredSubscriber
+	// bufferedSubscribers is a list of channels allocated by SubscribeBuffered which drop the oldest settings when full
+	bufferedSubscribers []chan *ArgoCDSettings
+	// unsubscribed holds a channel per subscribed channel which is closed when the channel unsubscribes, so pending
+	// notifications to it are abandoned
+	unsubscribed map[chan<- *ArgoCDSettings]chan struct{}
+	// lastNotified holds the settings sent in the most recent notification
+	lastNotified *ArgoCDSettings
 	// mutex protects concurrency sensitive parts of settings manager: access to subscribers list and initialization flag
 	mutex             *sync.Mutex
 	initContextCancel func()
+	// initializing is closed when the running initialization of the informers completes, nil if none is running
+	initializing chan struct{}
+	// initErr holds the result of the last initialization of the informers
+	initErr error
+	// initialNotifyOnce ensures subscribers are notified about the initially loaded settings only once
+	initialNotifyOnce sync.Once
+	// installationIDMutex serializes generation of the installation ID
+	installationIDMutex sync.Mutex
+	// cacheMutex protects the values cached from the informer caches, which are invalidated on informer events
+	cacheMutex sync.RWMutex
+	// cacheGeneration is incremented on each invalidation, so values read before an invalidation aren't cached
+	cacheGeneration uint64
+	// appInstanceLabelKey caches the result of GetAppInstanceLabelKey, empty if not cached
+	appInstanceLabelKey string
+	// reloadedKeys holds the ArgoCDConfigMap values read by ReloadKey, nil for removed keys. They override the values
+	// of the informer cache until it holds another version than reloadedBaseVersion.
+	reloadedKeys        map[string]*string
+	reloadedBaseVersion string
+	// resourceActions and fallbackResourceActions hold the informer caches of the ArgoCDResourceActionsConfigMap of
+	// the primary and the fallback namespace. The informers are started on first use, since only the components
+	// executing resource actions need them.
+	resourceActions         v1listers.ConfigMapLister
+	fallbackResourceActions v1listers.ConfigMapLister
+	// resourceActionsMutex protects starting the resource actions informers
+	resourceActionsMutex sync.Mutex
+}
+
+// filteredSubscriber is a subscriber interested only in changes of the given settings sections
+type filteredSubscriber struct {
+	sections []string
+	ch       chan<- *ArgoCDSettings
+}
+
+// settingsSections maps the logical settings section names accepted by SubscribeFiltered to functions
+// which extract the comparable values of that section
+var settingsSections = map[string]func(s *ArgoCDSettings) interface{}{
+	"url": func(s *ArgoCDSettings) interface{} {
+		return s.URL
+	},
+	"admin": func(s *ArgoCDSettings) interface{} {
+		return []interface{}{s.AdminPasswordHash, s.AdminPasswordMtime}
+	},
+	"dex": func(s *ArgoCDSettings) interface{} {
+		return s.DexConfig
+	},
+	"oidc": func(s *ArgoCDSettings) interface{} {
+		return s.OIDCConfigRAW
+	},
+	"signature": func(s *ArgoCDSettings) interface{} {
+		return s.ServerSignature
+	},
+	"tls": func(s *ArgoCDSettings) interface{} {
+		if s.Certificate == nil {
+			return nil
+		}
+		return s.Certificate.Certificate
+	},
+	"webhook": func(s *ArgoCDSettings) interface{} {
+		return []string{s.WebhookGitHubSecret, s.WebhookGitLabSecret, s.WebhookBitbucketUUID, s.WebhookBitbucketServerSecret}
+	},
+	"secrets": func(s *ArgoCDSettings) interface{} {
+		return s.Secrets
+	},
+	"repositories": func(s *ArgoCDSettings) interface{} {
+		return []interface{}{s.Repositories, s.RepositoryCredentials, s.HelmRepositories}
+	},
+}
+
+// sectionsChanged returns true if any of the given sections differs between the old and new settings
+func sectionsChanged(oldSettings, newSettings *ArgoCDSettings, sections []string) bool {
+	if oldSettings == nil {
+		return true
+	}
+	for _, section := range sections {
+		extract, ok := settingsSections[section]
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(extract(oldSettings), extract(newSettings)) {
+			return true
+		}
+	}
+	return false
 }
 
+// ErrNoServerSignature is returned when a value derived from the server signature is requested before the
+// signature has been initialized
+var ErrNoServerSignature = errors.New("server signature is not initialized")
+
 type incompleteSettingsError struct {
 	message string
 }
@@ -156,6 +610,34 @@ func (e *incompleteSettingsError) Error() string {
 	return e.message
 }
 
+// missingSecretKeyError returns the error reported if a required key of the ArgoCDSecret is missing
+func missingSecretKeyError(key string) error {
+	return &incompleteSettingsError{message: fmt.Sprintf("%s is missing", key)}
+}
+
+// requiredSecretKeys are the ArgoCDSecret keys without which the settings are incomplete
+var requiredSecretKeys = []string{settingAdminPasswordHashKey, settingServerSignatureKey}
+
+// CheckRequiredSecretKeys verifies that the ArgoCDSecret contains all required keys. Unlike GetSettings, which
+// returns the first error only, the returned error names each missing key.
+func (mgr *SettingsManager) CheckRequiredSecretKeys() error {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return err
+	}
+	argoCDSecret, err := mgr.getArgoCDSecret()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, key := range requiredSecretKeys {
+		if _, ok := argoCDSecret.Data[key]; !ok {
+			errs = append(errs, missingSecretKeyError(key))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 func (mgr *SettingsManager) GetSecretsLister() (v1listers.SecretLister, error) {
 	err := mgr.ensureSynced(false)
 	if err != nil {
@@ -169,11 +651,203 @@ func (mgr *SettingsManager) getConfigMap() (*apiv1.ConfigMap, error) {
 	if err != nil {
 		return nil, err
 	}
-	argoCDCM, err := mgr.configmaps.ConfigMaps(mgr.namespace).Get(common.ArgoCDConfigMapName)
+	return mgr.getArgoCDConfigMap()
+}
+
+// getArgoCDConfigMap returns the ArgoCDConfigMap from the informer cache, merged with the ConfigMap of the fallback
+// namespace if configured
+func (mgr *SettingsManager) getArgoCDConfigMap() (*apiv1.ConfigMap, error) {
+	argoCDCM, err := mgr.configmaps.ConfigMaps(mgr.namespace).Get(mgr.configMapName)
+	if err == nil {
+		argoCDCM = mgr.applyReloadedKeys(argoCDCM)
+	}
+	if mgr.fallbackNamespace == "" {
+		return argoCDCM, err
+	}
+	if err != nil && !apierr.IsNotFound(err) {
+		return nil, err
+	}
+	fallbackCM, fallbackErr := mgr.fallbackConfigmaps.ConfigMaps(mgr.fallbackNamespace).Get(mgr.configMapName)
+	if fallbackErr != nil {
+		if apierr.IsNotFound(fallbackErr) {
+			return argoCDCM, err
+		}
+		return nil, fallbackErr
+	}
 	if err != nil {
+		return fallbackCM, nil
+	}
+	argoCDCM = argoCDCM.DeepCopy()
+	if argoCDCM.Data == nil {
+		argoCDCM.Data = make(map[string]string)
+	}
+	for k, v := range fallbackCM.Data {
+		if _, ok := argoCDCM.Data[k]; !ok {
+			argoCDCM.Data[k] = v
+		}
+	}
+	return argoCDCM, nil
+}
+
+// getArgoCDSecret returns the ArgoCDSecret from the informer cache, merged with the secret of the fallback namespace
+// if configured
+func (mgr *SettingsManager) getArgoCDSecret() (*apiv1.Secret, error) {
+	argoCDSecret, err := mgr.secrets.Secrets(mgr.namespace).Get(mgr.secretName)
+	if mgr.fallbackNamespace == "" {
+		return argoCDSecret, err
+	}
+	if err != nil && !apierr.IsNotFound(err) {
 		return nil, err
 	}
-	return argoCDCM, err
+	fallbackSecret, fallbackErr := mgr.fallbackSecrets.Secrets(mgr.fallbackNamespace).Get(mgr.secretName)
+	if fallbackErr != nil {
+		if apierr.IsNotFound(fallbackErr) {
+			return argoCDSecret, err
+		}
+		return nil, fallbackErr
+	}
+	if err != nil {
+		return fallbackSecret, nil
+	}
+	argoCDSecret = argoCDSecret.DeepCopy()
+	if argoCDSecret.Data == nil {
+		argoCDSecret.Data = make(map[string][]byte)
+	}
+	for k, v := range fallbackSecret.Data {
+		if _, ok := argoCDSecret.Data[k]; !ok {
+			argoCDSecret.Data[k] = v
+		}
+	}
+	return argoCDSecret, nil
+}
+
+// dropFallbackConfigMapKeys removes the keys which the given ConfigMap data of the primary namespace didn't contain
+// before saving and whose value equals the value of the fallback namespace. Settings read from the fallback namespace
+// are read-only, so saving the settings doesn't copy them into the primary namespace.
+func (mgr *SettingsManager) dropFallbackConfigMapKeys(originalKeys map[string]bool, data map[string]string) error {
+	if mgr.fallbackNamespace == "" {
+		return nil
+	}
+	fallbackCM, err := mgr.fallbackConfigmaps.ConfigMaps(mgr.fallbackNamespace).Get(mgr.configMapName)
+	if err != nil {
+		if apierr.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	for k, v := range data {
+		if fallbackValue, ok := fallbackCM.Data[k]; ok && !originalKeys[k] && fallbackValue == v {
+			delete(data, k)
+		}
+	}
+	return nil
+}
+
+// dropFallbackSecretKeys works like dropFallbackConfigMapKeys for the secret data
+func (mgr *SettingsManager) dropFallbackSecretKeys(originalKeys map[string]bool, data map[string][]byte) error {
+	if mgr.fallbackNamespace == "" {
+		return nil
+	}
+	fallbackSecret, err := mgr.fallbackSecrets.Secrets(mgr.fallbackNamespace).Get(mgr.secretName)
+	if err != nil {
+		if apierr.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	for k, v := range data {
+		if fallbackValue, ok := fallbackSecret.Data[k]; ok && !originalKeys[k] && bytes.Equal(fallbackValue, v) {
+			delete(data, k)
+		}
+	}
+	return nil
+}
+
+// parseBool parses "true", "false", "1" and "0" case-insensitively
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value '%s'", value)
+}
+
+// getBool returns the boolean value of the given key, or the default if the value is empty. Invalid values are
+// logged and the default is returned.
+func getBool(key, value string, def bool) bool {
+	if value == "" {
+		return def
+	}
+	b, err := parseBool(value)
+	if err != nil {
+		log.WithField(logFieldSettingKey, key).Warnf("invalid value '%s' of %s, using default %v", value, key, def)
+		return def
+	}
+	return b
+}
+
+// applyBool sets the boolean value of the given key unless the current value already resolves to it, so keys which
+// are unchanged are left as they are. The key is removed if the value is the default.
+func applyBool(data map[string]string, key string, value bool, def bool) {
+	if getBool(key, data[key], def) == value {
+		return
+	}
+	if value == def {
+		delete(data, key)
+	} else {
+		data[key] = strconv.FormatBool(value)
+	}
+}
+
+// GetBoolSetting returns the boolean value of the given argocd-cm key, or the default if the key is not set.
+// An error is returned if the value is not one of true, false, 1 or 0.
+func (mgr *SettingsManager) GetBoolSetting(key string, def bool) (bool, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return def, err
+	}
+	value, ok := argoCDCM.Data[key]
+	if !ok {
+		return def, nil
+	}
+	b, err := parseBool(value)
+	if err != nil {
+		return def, fmt.Errorf("%s: %v", key, err)
+	}
+	return b, nil
+}
+
+// GetStringSetting returns the value of the given argocd-cm key, or the default if the key is not set
+func (mgr *SettingsManager) GetStringSetting(key, def string) (string, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return def, err
+	}
+	value, ok := argoCDCM.Data[key]
+	if !ok {
+		return def, nil
+	}
+	return value, nil
+}
+
+// GetIntSetting returns the integer value of the given argocd-cm key, or the default if the key is not set.
+// An error is returned if the value is not an integer.
+func (mgr *SettingsManager) GetIntSetting(key string, def int) (int, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return def, err
+	}
+	value, ok := argoCDCM.Data[key]
+	if !ok {
+		return def, nil
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return def, fmt.Errorf("%s: invalid integer value '%s'", key, value)
+	}
+	return i, nil
 }
 
 func (mgr *SettingsManager) GetResourcesFilter() (*ResourcesFilter, error) {
@@ -203,17 +877,107 @@ func (mgr *SettingsManager) GetResourcesFilter() (*ResourcesFilter, error) {
 }
 
 func (mgr *SettingsManager) GetAppInstanceLabelKey() (string, error) {
+	mgr.cacheMutex.RLock()
+	label, generation := mgr.appInstanceLabelKey, mgr.cacheGeneration
+	mgr.cacheMutex.RUnlock()
+	if label != "" {
+		return label, nil
+	}
 	argoCDCM, err := mgr.getConfigMap()
 	if err != nil {
 		return "", err
 	}
-	label := argoCDCM.Data[settingsApplicationInstanceLabelKey]
+	label = argoCDCM.Data[settingsApplicationInstanceLabelKey]
 	if label == "" {
-		return common.LabelKeyAppInstance, nil
+		label = common.LabelKeyAppInstance
 	}
+	mgr.cacheMutex.Lock()
+	if mgr.cacheGeneration == generation {
+		mgr.appInstanceLabelKey = label
+	}
+	mgr.cacheMutex.Unlock()
 	return label, nil
 }
 
+// ReloadKey reads the given key of the ArgoCDConfigMap from the API server, bypassing the informer cache, and
+// overrides the cached value and derived values accordingly until the informer observes a newer version of the
+// ConfigMap. This allows to observe a changed key immediately instead of waiting for the informer.
+func (mgr *SettingsManager) ReloadKey(key string) error {
+	if err := mgr.ensureSynced(false); err != nil {
+		return err
+	}
+	latest, err := mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(mgr.configMapName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	baseVersion := ""
+	cached, err := mgr.configmaps.ConfigMaps(mgr.namespace).Get(mgr.configMapName)
+	if err == nil {
+		baseVersion = cached.ResourceVersion
+	} else if !apierr.IsNotFound(err) {
+		return err
+	}
+	var value *string
+	if v, ok := latest.Data[key]; ok {
+		value = &v
+	}
+
+	mgr.cacheMutex.Lock()
+	if mgr.reloadedKeys == nil || mgr.reloadedBaseVersion != baseVersion {
+		mgr.reloadedKeys = make(map[string]*string)
+		mgr.reloadedBaseVersion = baseVersion
+	}
+	mgr.reloadedKeys[key] = value
+	mgr.cacheMutex.Unlock()
+
+	mgr.invalidateCache()
+	return nil
+}
+
+// applyReloadedKeys returns the given cached ArgoCDConfigMap with the values read by ReloadKey. The values are
+// dropped once the informer cache holds another version of the ConfigMap than the one they were read for.
+func (mgr *SettingsManager) applyReloadedKeys(argoCDCM *apiv1.ConfigMap) *apiv1.ConfigMap {
+	mgr.cacheMutex.Lock()
+	defer mgr.cacheMutex.Unlock()
+	if len(mgr.reloadedKeys) == 0 {
+		return argoCDCM
+	}
+	if argoCDCM.ResourceVersion != mgr.reloadedBaseVersion {
+		mgr.reloadedKeys = nil
+		return argoCDCM
+	}
+	argoCDCM = argoCDCM.DeepCopy()
+	if argoCDCM.Data == nil {
+		argoCDCM.Data = make(map[string]string)
+	}
+	for key, value := range mgr.reloadedKeys {
+		if value != nil {
+			argoCDCM.Data[key] = *value
+		} else {
+			delete(argoCDCM.Data, key)
+		}
+	}
+	return argoCDCM
+}
+
+// invalidateCache drops the values cached from the informer caches
+func (mgr *SettingsManager) invalidateCache() {
+	mgr.cacheMutex.Lock()
+	defer mgr.cacheMutex.Unlock()
+	mgr.cacheGeneration++
+	mgr.appInstanceLabelKey = ""
+}
+
+// GetDefaultDestinationNamespace returns the destination namespace of applications which don't specify one. An empty
+// string is returned if no default is configured.
+func (mgr *SettingsManager) GetDefaultDestinationNamespace() (string, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return "", err
+	}
+	return argoCDCM.Data[defaultDestinationNamespaceKey], nil
+}
+
 func (mgr *SettingsManager) GetConfigManagementPlugins() ([]v1alpha1.ConfigManagementPlugin, error) {
 	argoCDCM, err := mgr.getConfigMap()
 	if err != nil {
@@ -242,438 +1006,2513 @@ func (mgr *SettingsManager) GetResourceOverrides() (map[string]v1alpha1.Resource
 			return nil, err
 		}
 	}
+	err = appendResourceOverridesFromSplitKeys(argoCDCM.Data, resourceOverrides)
+	if err != nil {
+		return nil, err
+	}
 
 	return resourceOverrides, nil
 }
 
-// GetSettings retrieves settings from the ArgoCDConfigMap and secret.
-func (mgr *SettingsManager) GetSettings() (*ArgoCDSettings, error) {
-	err := mgr.ensureSynced(false)
+// GetHealthChecks returns the custom health check Lua scripts of the resource overrides keyed by their group and kind
+func (mgr *SettingsManager) GetHealthChecks() (map[schema.GroupKind]string, error) {
+	resourceOverrides, err := mgr.GetResourceOverrides()
 	if err != nil {
 		return nil, err
 	}
-	argoCDCM, err := mgr.configmaps.ConfigMaps(mgr.namespace).Get(common.ArgoCDConfigMapName)
-	if err != nil {
-		return nil, err
+	healthChecks := make(map[schema.GroupKind]string)
+	for key, override := range resourceOverrides {
+		if override.HealthLua == "" {
+			continue
+		}
+		healthChecks[parseGroupKind(key)] = override.HealthLua
 	}
-	argoCDSecret, err := mgr.secrets.Secrets(mgr.namespace).Get(common.ArgoCDSecretName)
-	if err != nil {
+	return healthChecks, nil
+}
+
+// GetResourceActions returns the resource actions keyed by their group and kind. Actions are read from the
+// ArgoCDResourceActionsConfigMap, keyed by <group>_<kind> or <kind>, and from the resource overrides of argocd-cm,
+// which take precedence over the ConfigMap.
+func (mgr *SettingsManager) GetResourceActions() (map[schema.GroupKind]string, error) {
+	actions := make(map[schema.GroupKind]string)
+	actionsCM, err := mgr.getResourceActionsConfigMap()
+	if err != nil && !apierr.IsNotFound(err) {
 		return nil, err
 	}
-	var settings ArgoCDSettings
-	var errs []error
-	if err := updateSettingsFromConfigMap(&settings, argoCDCM); err != nil {
-		errs = append(errs, err)
+	if err == nil {
+		for key, value := range actionsCM.Data {
+			overrideKey, err := convertToOverrideKey(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key '%s' in %s: %v", key, common.ArgoCDResourceActionsConfigMapName, err)
+			}
+			if strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("actions of '%s' in %s must not be empty", key, common.ArgoCDResourceActionsConfigMapName)
+			}
+			actions[parseGroupKind(overrideKey)] = value
+		}
 	}
-	if err := updateSettingsFromSecret(&settings, argoCDSecret); err != nil {
-		errs = append(errs, err)
+	resourceOverrides, err := mgr.GetResourceOverrides()
+	if err != nil {
+		return nil, err
 	}
-	if len(errs) > 0 {
-		return &settings, errs[0]
+	for key, override := range resourceOverrides {
+		if strings.TrimSpace(override.Actions) == "" {
+			continue
+		}
+		actions[parseGroupKind(key)] = override.Actions
 	}
-	return &settings, nil
+	return actions, nil
 }
 
-// MigrateLegacyRepoSettings migrates legacy (v0.10 and below) repo secrets into the v0.11 configmap
-func (mgr *SettingsManager) MigrateLegacyRepoSettings(settings *ArgoCDSettings) error {
-	err := mgr.ensureSynced(false)
-	if err != nil {
-		return err
+// getResourceActionsConfigMap returns the ArgoCDResourceActionsConfigMap from the informer cache, or the ConfigMap of
+// the fallback namespace if it doesn't exist in the primary namespace. The returned ConfigMap must not be modified.
+func (mgr *SettingsManager) getResourceActionsConfigMap() (*apiv1.ConfigMap, error) {
+	mgr.resourceActionsMutex.Lock()
+	if mgr.resourceActions == nil {
+		if err := mgr.startResourceActionsInformers(); err != nil {
+			mgr.resourceActionsMutex.Unlock()
+			return nil, err
+		}
 	}
+	resourceActions, fallbackResourceActions := mgr.resourceActions, mgr.fallbackResourceActions
+	mgr.resourceActionsMutex.Unlock()
 
-	labelSelector := labels.NewSelector()
-	req, err := labels.NewRequirement(common.LabelKeySecretType, selection.Equals, []string{"repository"})
-	if err != nil {
-		return err
+	cm, err := resourceActions.ConfigMaps(mgr.namespace).Get(common.ArgoCDResourceActionsConfigMapName)
+	if err == nil || !apierr.IsNotFound(err) || fallbackResourceActions == nil {
+		return cm, err
 	}
-	labelSelector = labelSelector.Add(*req)
-	repoSecrets, err := mgr.secrets.Secrets(mgr.namespace).List(labelSelector)
+	return fallbackResourceActions.ConfigMaps(mgr.fallbackNamespace).Get(common.ArgoCDResourceActionsConfigMapName)
+}
+
+// startResourceActionsInformers starts the informers of the ArgoCDResourceActionsConfigMap and waits until they are
+// synced. The informers run until the context of the settings manager is cancelled.
+func (mgr *SettingsManager) startResourceActionsInformers() error {
+	ctx, cancel := context.WithCancel(mgr.ctx)
+	synced := false
+	defer func() {
+		// stop the informers of a failed attempt
+		if !synced {
+			cancel()
+		}
+	}()
+	informer := startConfigMapInformer(ctx, mgr.clientset, mgr.namespace, common.ArgoCDResourceActionsConfigMapName)
+	hasSynced := []cache.InformerSynced{informer.HasSynced}
+	var fallbackInformer cache.SharedIndexInformer
+	if mgr.fallbackNamespace != "" {
+		fallbackInformer = startConfigMapInformer(ctx, mgr.clientset, mgr.fallbackNamespace, common.ArgoCDResourceActionsConfigMapName)
+		hasSynced = append(hasSynced, fallbackInformer.HasSynced)
+	}
+	syncCtx, syncCancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer syncCancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), hasSynced...) {
+		return fmt.Errorf("Timed out waiting for %s cache to sync", common.ArgoCDResourceActionsConfigMapName)
+	}
+	synced = true
+	mgr.resourceActions = v1listers.NewConfigMapLister(informer.GetIndexer())
+	if fallbackInformer != nil {
+		mgr.fallbackResourceActions = v1listers.NewConfigMapLister(fallbackInformer.GetIndexer())
+	}
+	return nil
+}
+
+// parseGroupKind parses a resource override key of the form group/kind, or kind for resources of the core group
+func parseGroupKind(key string) schema.GroupKind {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return schema.GroupKind{Group: key[:i], Kind: key[i+1:]}
+	}
+	return schema.GroupKind{Kind: key}
+}
+
+// GetFeatureFlags loads the experimental feature flags from argocd-cm ConfigMap
+func (mgr *SettingsManager) GetFeatureFlags() (map[string]bool, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	features := make(map[string]bool)
+	if value, ok := argoCDCM.Data[featuresKey]; ok {
+		if err := yaml.Unmarshal([]byte(value), &features); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", featuresKey, err)
+		}
+	}
+	return features, nil
+}
+
+// IsFeatureEnabled returns whether the given experimental feature is enabled. Unknown features are disabled.
+func (mgr *SettingsManager) IsFeatureEnabled(name string) bool {
+	features, err := mgr.GetFeatureFlags()
+	if err != nil {
+		log.WithField(logFieldSettingKey, featuresKey).Warnf("Unable to load feature flags, %s is disabled: %v", name, err)
+		return false
+	}
+	return features[name]
+}
+
+// ResourceCompareOptions holds the options which control how live and target resources are compared
+type ResourceCompareOptions struct {
+	// IgnoreAggregatedRoles ignores the rules of aggregated ClusterRoles, which are populated by the control plane
+	IgnoreAggregatedRoles bool `json:"ignoreAggregatedRoles,omitempty"`
+	// IgnoreResourceStatusField determines for which resources the status field is ignored: crd, all or none
+	IgnoreResourceStatusField string `json:"ignoreResourceStatusField,omitempty"`
+}
+
+// GetDefaultResourceCompareOptions returns the compare options used if resource.compareoptions is not configured:
+// aggregated roles are compared and the status field of custom resources is ignored
+func GetDefaultResourceCompareOptions() ResourceCompareOptions {
+	return ResourceCompareOptions{
+		IgnoreAggregatedRoles:     false,
+		IgnoreResourceStatusField: "crd",
+	}
+}
+
+// GetResourceCompareOptions loads the resource compare options from argocd-cm ConfigMap
+func (mgr *SettingsManager) GetResourceCompareOptions() (ResourceCompareOptions, error) {
+	compareOptions := GetDefaultResourceCompareOptions()
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return compareOptions, err
+	}
+	if value, ok := argoCDCM.Data[resourceCompareOptionsKey]; ok {
+		err := yaml.Unmarshal([]byte(value), &compareOptions)
+		if err != nil {
+			return compareOptions, err
+		}
+	}
+	return compareOptions, nil
+}
+
+// DefaultProjectRestrictions holds the cluster-wide defaults restricting new applications
+type DefaultProjectRestrictions struct {
+	// Project is the project of new applications which don't specify one
+	Project string
+	// SourceRepos are the source repositories new applications may use
+	SourceRepos []string
+	// Destinations are the destination clusters and namespaces new applications may use
+	Destinations []v1alpha1.ApplicationDestination
+}
+
+// GetDefaultProjectRestrictions loads the default project restrictions from argocd-cm ConfigMap. Nil is returned
+// if no restrictions are configured.
+func (mgr *SettingsManager) GetDefaultProjectRestrictions() (*DefaultProjectRestrictions, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	project := argoCDCM.Data[defaultProjectKey]
+	sourceReposStr := argoCDCM.Data[defaultSourceReposKey]
+	destinationsStr := argoCDCM.Data[defaultDestinationsKey]
+	if project == "" && sourceReposStr == "" && destinationsStr == "" {
+		return nil, nil
+	}
+	restrictions := DefaultProjectRestrictions{
+		Project:      project,
+		Destinations: make([]v1alpha1.ApplicationDestination, 0),
+	}
+	restrictions.SourceRepos, err = parseStringList(sourceReposStr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", defaultSourceReposKey, err)
+	}
+	if destinationsStr != "" {
+		if err := yaml.Unmarshal([]byte(destinationsStr), &restrictions.Destinations); err != nil {
+			return nil, fmt.Errorf("%s: %v", defaultDestinationsKey, err)
+		}
+	}
+	return &restrictions, nil
+}
+
+// appendResourceOverridesFromSplitKeys merges customizations stored under split keys of the form
+// resource.customizations.<type>.<group>_<kind> into the given overrides. Split keys take precedence
+// over the same customization defined in the monolithic resource.customizations key.
+func appendResourceOverridesFromSplitKeys(cmData map[string]string, resourceOverrides map[string]v1alpha1.ResourceOverride) error {
+	for k, v := range cmData {
+		if !strings.HasPrefix(k, resourceCustomizationsKey+".") {
+			continue
+		}
+		parts := strings.SplitN(k, ".", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		overrideKey, err := convertToOverrideKey(parts[3])
+		if err != nil {
+			return err
+		}
+		overrideVal := resourceOverrides[overrideKey]
+		switch parts[2] {
+		case "health":
+			overrideVal.HealthLua = v
+		case "ignoreDifferences":
+			if err := validateIgnoreDifferences(v); err != nil {
+				return fmt.Errorf("invalid value of '%s': %v", k, err)
+			}
+			overrideVal.IgnoreDifferences = v
+		case "actions":
+			overrideVal.Actions = v
+		default:
+			log.WithField(logFieldSettingKey, k).Warnf("ignoring unknown resource customization type '%s' in key '%s'", parts[2], k)
+			continue
+		}
+		resourceOverrides[overrideKey] = overrideVal
+	}
+	return nil
+}
+
+// overrideIgnoreDiff holds the paths a resource override ignores during comparison
+type overrideIgnoreDiff struct {
+	JSONPointers      []string `json:"jsonPointers,omitempty"`
+	JQPathExpressions []string `json:"jqPathExpressions,omitempty"`
+}
+
+// validateIgnoreDifferences verifies the given value is a list of well-formed JSON pointers and/or jq path expressions
+func validateIgnoreDifferences(value string) error {
+	var ignoreDiff overrideIgnoreDiff
+	err := yaml.Unmarshal([]byte(value), &ignoreDiff)
 	if err != nil {
 		return err
 	}
-	settings.Repositories = make([]RepoCredentials, len(repoSecrets))
-	for i, s := range repoSecrets {
-		_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(s)
+	if len(ignoreDiff.JSONPointers) == 0 && len(ignoreDiff.JQPathExpressions) == 0 {
+		return fmt.Errorf("at least one of jsonPointers or jqPathExpressions must be specified")
+	}
+	for _, pointer := range ignoreDiff.JSONPointers {
+		if !strings.HasPrefix(pointer, "/") {
+			return fmt.Errorf("json pointer '%s' must start with '/'", pointer)
+		}
+		for i := strings.Index(pointer, "~"); i >= 0; i = strings.Index(pointer, "~") {
+			if i+1 >= len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1') {
+				return fmt.Errorf("json pointer '%s' contains an invalid escape sequence", pointer)
+			}
+			pointer = pointer[i+2:]
+		}
+	}
+	for _, expression := range ignoreDiff.JQPathExpressions {
+		if strings.TrimSpace(expression) == "" {
+			return fmt.Errorf("jq path expression must not be empty")
+		}
+	}
+	return nil
+}
+
+// convertToOverrideKey converts a split key suffix of the form <group>_<kind> (or just <kind> for the
+// core API group) into the <group>/<kind> form used by resource.customizations.
+func convertToOverrideKey(groupKind string) (string, error) {
+	parts := strings.Split(groupKind, "_")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return fmt.Sprintf("%s/%s", parts[0], parts[1]), nil
+	} else if len(parts) == 1 && groupKind != "" {
+		return groupKind, nil
+	}
+	return "", fmt.Errorf("group kind should be in format '<group>_<kind>' or '<kind>', got '%s'", groupKind)
+}
+
+// getSettingsObjects retrieves the ArgoCDConfigMap and secret from the informer caches.
+func (mgr *SettingsManager) getSettingsObjects() (*apiv1.ConfigMap, *apiv1.Secret, error) {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	argoCDCM, err := mgr.getArgoCDConfigMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	argoCDSecret, err := mgr.getArgoCDSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	return argoCDCM, argoCDSecret, nil
+}
+
+// ExportManifests returns the ArgoCDConfigMap and the ArgoCDSecret as a multi-document YAML which can be applied
+// using kubectl. Secret values are replaced by placeholders unless includeSecrets is true.
+func (mgr *SettingsManager) ExportManifests(includeSecrets bool) (string, error) {
+	argoCDCM, argoCDSecret, err := mgr.getSettingsObjects()
+	if err != nil {
+		return "", err
+	}
+	exportedCM := apiv1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        argoCDCM.Name,
+			Namespace:   argoCDCM.Namespace,
+			Labels:      argoCDCM.Labels,
+			Annotations: argoCDCM.Annotations,
+		},
+		Data: argoCDCM.Data,
+	}
+	exportedSecret := apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        argoCDSecret.Name,
+			Namespace:   argoCDSecret.Namespace,
+			Labels:      argoCDSecret.Labels,
+			Annotations: argoCDSecret.Annotations,
+		},
+		Type: argoCDSecret.Type,
+	}
+	if includeSecrets {
+		exportedSecret.Data = argoCDSecret.Data
+	} else {
+		exportedSecret.StringData = make(map[string]string, len(argoCDSecret.Data))
+		for k := range argoCDSecret.Data {
+			exportedSecret.StringData[k] = redactedValue
+		}
+	}
+	cmYAML, err := yaml.Marshal(exportedCM)
+	if err != nil {
+		return "", err
+	}
+	secretYAML, err := yaml.Marshal(exportedSecret)
+	if err != nil {
+		return "", err
+	}
+	return string(cmYAML) + "---\n" + string(secretYAML), nil
+}
+
+// yamlDocumentSeparatorRegex matches the separators of a multi-document YAML
+var yamlDocumentSeparatorRegex = regexp.MustCompile(`(?m)^---\s*$`)
+
+// ImportManifests applies the ArgoCDConfigMap and ArgoCDSecret manifests of the given multi-document YAML, as produced
+// by ExportManifests. The existing keys are replaced unless merge is true, in which case only the imported keys are
+// updated. Redacted secret values are ignored. Manifests of any other object are rejected and nothing is applied.
+func (mgr *SettingsManager) ImportManifests(yamlDoc string, merge bool) error {
+	var importedCM *apiv1.ConfigMap
+	var importedSecret *apiv1.Secret
+	for _, doc := range yamlDocumentSeparatorRegex.Split(yamlDoc, -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal([]byte(doc), &typeMeta); err != nil {
+			return err
+		}
+		switch typeMeta.Kind {
+		case "ConfigMap":
+			var cm apiv1.ConfigMap
+			if err := yaml.Unmarshal([]byte(doc), &cm); err != nil {
+				return err
+			}
+			if cm.Name != mgr.configMapName || importedCM != nil {
+				return fmt.Errorf("unexpected ConfigMap '%s', only a single %s is allowed", cm.Name, mgr.configMapName)
+			}
+			importedCM = &cm
+		case "Secret":
+			var secret apiv1.Secret
+			if err := yaml.Unmarshal([]byte(doc), &secret); err != nil {
+				return err
+			}
+			if secret.Name != mgr.secretName || importedSecret != nil {
+				return fmt.Errorf("unexpected Secret '%s', only a single %s is allowed", secret.Name, mgr.secretName)
+			}
+			importedSecret = &secret
+		default:
+			return fmt.Errorf("unexpected resource kind '%s', only ConfigMap %s and Secret %s are allowed", typeMeta.Kind, mgr.configMapName, mgr.secretName)
+		}
+	}
+
+	if importedCM != nil {
+		argoCDCM, createCM, err := mgr.getConfigMapForUpsert(true)
 		if err != nil {
 			return err
 		}
-		cred := RepoCredentials{URL: string(s.Data["repository"])}
-		if username, ok := s.Data["username"]; ok && string(username) != "" {
-			cred.UsernameSecret = &apiv1.SecretKeySelector{
-				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
-				Key:                  "username",
+		if !merge {
+			argoCDCM.Data = make(map[string]string)
+		}
+		for k, v := range importedCM.Data {
+			argoCDCM.Data[k] = v
+		}
+		if createCM {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Create(argoCDCM)
+		} else {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Update(argoCDCM)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if importedSecret != nil {
+		argoCDSecret, createSecret, err := mgr.getSecretForUpsert(true)
+		if err != nil {
+			return err
+		}
+		data := make(map[string][]byte)
+		for k, v := range importedSecret.Data {
+			data[k] = v
+		}
+		for k, v := range importedSecret.StringData {
+			if v == redactedValue {
+				log.WithField(logFieldSettingKey, k).Warnf("ignoring redacted value of %s", k)
+				continue
 			}
+			data[k] = []byte(v)
 		}
-		if password, ok := s.Data["password"]; ok && string(password) != "" {
-			cred.PasswordSecret = &apiv1.SecretKeySelector{
-				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
-				Key:                  "password",
+		if !merge {
+			// keep existing values of redacted keys
+			for k := range importedSecret.StringData {
+				if _, ok := data[k]; !ok {
+					if v, ok := argoCDSecret.Data[k]; ok {
+						data[k] = v
+					}
+				}
+			}
+			argoCDSecret.Data = make(map[string][]byte)
+		}
+		for k, v := range data {
+			argoCDSecret.Data[k] = v
+		}
+		if createSecret {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Create(argoCDSecret)
+		} else {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(argoCDSecret)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mgr.ResyncInformers()
+}
+
+// GetTrustedCACerts returns the system certificate pool extended by the PEM encoded certificates of the
+// ArgoCDTLSCertsConfigMap. The system pool is returned as is if the ConfigMap does not exist.
+func (mgr *SettingsManager) GetTrustedCACerts() (*x509.CertPool, error) {
+	certPool, err := x509.SystemCertPool()
+	if err != nil {
+		log.Warnf("unable to load system certificate pool: %v", err)
+		certPool = x509.NewCertPool()
+	}
+	certsCM, err := mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(common.ArgoCDTLSCertsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierr.IsNotFound(err) {
+			return certPool, nil
+		}
+		return nil, err
+	}
+	hosts := make([]string, 0, len(certsCM.Data))
+	for host := range certsCM.Data {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if !certPool.AppendCertsFromPEM([]byte(certsCM.Data[host])) {
+			return nil, fmt.Errorf("failed to parse PEM certificates for host '%s' in %s", host, common.ArgoCDTLSCertsConfigMapName)
+		}
+	}
+	return certPool, nil
+}
+
+// GetSSHKnownHosts returns the known_hosts data of the ArgoCDKnownHostsConfigMap, or an empty string if it does not exist.
+func (mgr *SettingsManager) GetSSHKnownHosts() (string, error) {
+	knownHostsCM, err := mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(common.ArgoCDKnownHostsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierr.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return knownHostsCM.Data[sshKnownHostsKey], nil
+}
+
+// AddSSHKnownHost validates the given known_hosts line and appends it to the ArgoCDKnownHostsConfigMap unless already present.
+func (mgr *SettingsManager) AddSSHKnownHost(entry string) error {
+	entry = strings.TrimSpace(entry)
+	_, _, _, _, rest, err := ssh.ParseKnownHosts([]byte(entry))
+	if err != nil {
+		return fmt.Errorf("invalid known_hosts entry '%s': %v", entry, err)
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return fmt.Errorf("invalid known_hosts entry '%s': expected a single line", entry)
+	}
+
+	createCM := false
+	knownHostsCM, err := mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(common.ArgoCDKnownHostsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !apierr.IsNotFound(err) {
+			return err
+		}
+		knownHostsCM = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: common.ArgoCDKnownHostsConfigMapName,
+			},
+		}
+		createCM = true
+	}
+	if knownHostsCM.Data == nil {
+		knownHostsCM.Data = make(map[string]string)
+	}
+	knownHosts := knownHostsCM.Data[sshKnownHostsKey]
+	for _, line := range strings.Split(knownHosts, "\n") {
+		if strings.TrimSpace(line) == entry {
+			return nil
+		}
+	}
+	if knownHosts != "" && !strings.HasSuffix(knownHosts, "\n") {
+		knownHosts += "\n"
+	}
+	knownHostsCM.Data[sshKnownHostsKey] = knownHosts + entry + "\n"
+
+	if createCM {
+		_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Create(knownHostsCM)
+	} else {
+		_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Update(knownHostsCM)
+	}
+	return err
+}
+
+// GetSettings retrieves settings from the ArgoCDConfigMap and secret.
+func (mgr *SettingsManager) GetSettings() (*ArgoCDSettings, error) {
+	argoCDCM, argoCDSecret, err := mgr.getSettingsObjects()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := newSettingsFromObjects(argoCDCM, argoCDSecret)
+	mgr.resolveExternalSecrets(settings)
+	return settings, err
+}
+
+// resolveExternalSecrets resolves the $secretName:key references of the given settings against the secrets of the
+// namespace, so they are available to consumers of the settings which have no access to the secrets
+func (mgr *SettingsManager) resolveExternalSecrets(settings *ArgoCDSettings) {
+	oidcConfig := settings.parseOIDCConfig()
+	if oidcConfig == nil || mgr.secrets == nil {
+		return
+	}
+	ref := oidcConfig.ClientSecret
+	if !strings.HasPrefix(ref, "$") || !strings.Contains(ref, ":") {
+		return
+	}
+	settings.externalSecrets = map[string]string{
+		ref: ReplaceStringSecretWithLister(ref, settings.Secrets, mgr.secrets.Secrets(mgr.namespace)),
+	}
+}
+
+// GetSettingsAllowIncomplete works like GetSettings, but returns the partially populated settings without an error if
+// only required secret keys (e.g. admin.password or server.secretkey) are missing. This is useful before
+// InitializeSettings has run. Fetch and parse errors are still returned.
+func (mgr *SettingsManager) GetSettingsAllowIncomplete() (*ArgoCDSettings, error) {
+	settings, err := mgr.GetSettings()
+	// ConfigMap parse errors take precedence over incomplete settings errors, so an incomplete settings error
+	// means that the ConfigMap was parsed successfully
+	if err != nil && isIncompleteSettingsError(err) {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// GetSettingsWithRaw retrieves settings along with copies of the raw ConfigMap data and secret data they were
+// parsed from. Secret values are redacted so the result is safe to surface.
+func (mgr *SettingsManager) GetSettingsWithRaw() (*ArgoCDSettings, map[string]string, map[string]string, error) {
+	argoCDCM, argoCDSecret, err := mgr.getSettingsObjects()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rawCM := make(map[string]string, len(argoCDCM.Data))
+	for k, v := range argoCDCM.Data {
+		rawCM[k] = v
+	}
+	rawSecret := make(map[string]string, len(argoCDSecret.Data))
+	for k := range argoCDSecret.Data {
+		rawSecret[k] = redactedValue
+	}
+	settings, err := newSettingsFromObjects(argoCDCM, argoCDSecret)
+	mgr.resolveExternalSecrets(settings)
+	return settings, rawCM, rawSecret, err
+}
+
+// newSettingsFromObjects parses settings from the given ArgoCDConfigMap and secret.
+func newSettingsFromObjects(argoCDCM *apiv1.ConfigMap, argoCDSecret *apiv1.Secret) (*ArgoCDSettings, error) {
+	var settings ArgoCDSettings
+	var errs []error
+	if err := updateSettingsFromConfigMap(&settings, argoCDCM); err != nil {
+		errs = append(errs, err)
+	}
+	if err := updateSettingsFromSecret(&settings, argoCDSecret); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return &settings, errs[0]
+	}
+	return &settings, nil
+}
+
+// GetInstallationID returns the stable, cluster-unique identifier of this Argo CD installation. The identifier is
+// generated and persisted in the ArgoCDConfigMap on first use. It is not a secret.
+func (mgr *SettingsManager) GetInstallationID() (string, error) {
+	mgr.installationIDMutex.Lock()
+	defer mgr.installationIDMutex.Unlock()
+	for attempt := 1; ; attempt++ {
+		argoCDCM, err := mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(mgr.configMapName, metav1.GetOptions{})
+		createCM := false
+		if err != nil {
+			if !apierr.IsNotFound(err) {
+				return "", err
 			}
+			argoCDCM = &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: mgr.configMapName,
+				},
+			}
+			createCM = true
+		}
+		if id := argoCDCM.Data[settingsInstallationIDKey]; id != "" {
+			return id, nil
+		}
+		id, err := newInstallationID()
+		if err != nil {
+			return "", err
+		}
+		if argoCDCM.Data == nil {
+			argoCDCM.Data = make(map[string]string)
+		}
+		argoCDCM.Data[settingsInstallationIDKey] = id
+		if createCM {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Create(argoCDCM)
+		} else {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Update(argoCDCM)
+		}
+		if err == nil {
+			log.Infof("Initialized installation ID %s", id)
+			return id, nil
+		}
+		if !(apierr.IsConflict(err) || apierr.IsAlreadyExists(err)) || attempt >= installationIDAttempts {
+			return "", err
+		}
+		// another replica initialized the ID concurrently, re-read it
+		log.Warnf("conflict when initializing installation ID, retrying")
+	}
+}
+
+// newInstallationID generates a random (version 4) UUID
+func newInstallationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Account capabilities
+const (
+	// AccountCapabilityLogin allows an account to log in using the UI or the CLI
+	AccountCapabilityLogin = "login"
+	// AccountCapabilityAPIKey allows an account to generate API keys
+	AccountCapabilityAPIKey = "apiKey"
+)
+
+// accountCapabilities are the valid account capabilities
+var accountCapabilities = []string{AccountCapabilityLogin, AccountCapabilityAPIKey}
+
+// Account is a local (non-SSO) account
+type Account struct {
+	Name         string
+	Enabled      bool
+	Capabilities []string
+}
+
+// HasCapability returns whether the account has the given capability
+func (a Account) HasCapability(capability string) bool {
+	return containsString(a.Capabilities, capability)
+}
+
+// GetAccounts returns the built-in admin account and the local accounts configured by accounts.<name> keys holding
+// the comma-separated account capabilities and accounts.<name>.enabled keys. Accounts are enabled by default.
+func (mgr *SettingsManager) GetAccounts() (map[string]Account, error) {
+	argoCDCM, err := mgr.getConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]Account)
+	accounts[common.ArgoCDAdminUsername] = Account{
+		Name:         common.ArgoCDAdminUsername,
+		Enabled:      getBool(settingAdminEnabledKey, argoCDCM.Data[settingAdminEnabledKey], true),
+		Capabilities: []string{AccountCapabilityLogin},
+	}
+
+	getAccount := func(name string) Account {
+		if account, ok := accounts[name]; ok {
+			return account
+		}
+		return Account{Name: name, Enabled: true}
+	}
+	for key, value := range argoCDCM.Data {
+		if !strings.HasPrefix(key, accountsKeyPrefix+".") {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(key, accountsKeyPrefix+"."), ".")
+		switch {
+		case len(parts) == 1 && parts[0] != "":
+			account := getAccount(parts[0])
+			account.Capabilities = make([]string, 0)
+			for _, capability := range strings.Split(value, ",") {
+				capability = strings.TrimSpace(capability)
+				if capability == "" {
+					continue
+				}
+				if !containsString(accountCapabilities, capability) {
+					return nil, fmt.Errorf("%s: unknown account capability '%s', must be one of %v", key, capability, accountCapabilities)
+				}
+				account.Capabilities = append(account.Capabilities, capability)
+			}
+			accounts[account.Name] = account
+		case len(parts) == 2 && parts[0] != "" && parts[1] == accountEnabledKeySuffix:
+			if parts[0] == common.ArgoCDAdminUsername {
+				return nil, fmt.Errorf("%s: the admin account is enabled using %s", key, settingAdminEnabledKey)
+			}
+			enabled, err := parseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			account := getAccount(parts[0])
+			account.Enabled = enabled
+			accounts[account.Name] = account
+		default:
+			log.WithField(logFieldSettingKey, key).Warnf("ignoring unknown account setting '%s'", key)
+		}
+	}
+	return accounts, nil
+}
+
+// Token holds the metadata of an API key token issued for an account
+type Token struct {
+	ID       string `json:"id"`
+	IssuedAt int64  `json:"iat"`
+}
+
+func accountTokensKey(account string) string {
+	return fmt.Sprintf("%s.%s.%s", accountsKeyPrefix, account, accountTokensKeySuffix)
+}
+
+func parseAccountTokens(data []byte) ([]Token, error) {
+	tokens := make([]Token, 0)
+	if len(data) == 0 {
+		return tokens, nil
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetAccountTokens returns the API key tokens issued for the given account
+func (mgr *SettingsManager) GetAccountTokens(account string) ([]Token, error) {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return nil, err
+	}
+	argoCDSecret, err := mgr.getArgoCDSecret()
+	if err != nil {
+		return nil, err
+	}
+	return parseAccountTokens(argoCDSecret.Data[accountTokensKey(account)])
+}
+
+// AddAccountToken stores the metadata of an API key token issued for the given account
+func (mgr *SettingsManager) AddAccountToken(account, tokenID string, issuedAt time.Time) error {
+	return mgr.updateAccountTokens(account, func(tokens []Token) ([]Token, error) {
+		for _, token := range tokens {
+			if token.ID == tokenID {
+				return nil, fmt.Errorf("token '%s' of account '%s' already exists", tokenID, account)
+			}
+		}
+		return append(tokens, Token{ID: tokenID, IssuedAt: issuedAt.Unix()}), nil
+	})
+}
+
+// RevokeAccountToken removes the metadata of an API key token issued for the given account, so that the token is
+// rejected
+func (mgr *SettingsManager) RevokeAccountToken(account, tokenID string) error {
+	return mgr.updateAccountTokens(account, func(tokens []Token) ([]Token, error) {
+		for i, token := range tokens {
+			if token.ID == tokenID {
+				return append(tokens[:i], tokens[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("token '%s' of account '%s' not found", tokenID, account)
+	})
+}
+
+// updateAccountTokens applies the given update to the latest tokens of the given account, retrying on conflicts
+func (mgr *SettingsManager) updateAccountTokens(account string, update func(tokens []Token) ([]Token, error)) error {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return err
+	}
+	key := accountTokensKey(account)
+	for attempt := 1; ; attempt++ {
+		argoCDSecret, createSecret, err := mgr.getSecretForUpsert(true)
+		if err != nil {
+			return err
+		}
+		tokens, err := parseAccountTokens(argoCDSecret.Data[key])
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		tokens, err = update(tokens)
+		if err != nil {
+			return err
+		}
+		if len(tokens) > 0 {
+			data, err := json.Marshal(tokens)
+			if err != nil {
+				return err
+			}
+			argoCDSecret.Data[key] = data
+		} else {
+			delete(argoCDSecret.Data, key)
+		}
+		if createSecret {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Create(argoCDSecret)
+		} else {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(argoCDSecret)
+		}
+		if err == nil {
+			break
+		}
+		if !(apierr.IsConflict(err) || apierr.IsAlreadyExists(err)) || attempt >= saveSettingsAttempts {
+			return err
+		}
+		log.Warnf("conflict when updating tokens of account '%s', retrying", account)
+	}
+	return mgr.ResyncInformers()
+}
+
+// Cluster holds the credentials of a cluster stored in a secret labeled as cluster secret
+type Cluster struct {
+	Name   string
+	Server string
+	Config v1alpha1.ClusterConfig
+}
+
+// GetClusters returns the clusters of the cluster secrets sorted by server URL. Malformed cluster secrets are skipped.
+func (mgr *SettingsManager) GetClusters() ([]Cluster, error) {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector := labels.NewSelector()
+	req, err := labels.NewRequirement(common.LabelKeySecretType, selection.Equals, []string{common.LabelValueSecretTypeCluster})
+	if err != nil {
+		return nil, err
+	}
+	labelSelector = labelSelector.Add(*req)
+	clusterSecrets, err := mgr.secrets.Secrets(mgr.namespace).List(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	clusters := make([]Cluster, 0, len(clusterSecrets))
+	for _, s := range clusterSecrets {
+		server := string(s.Data["server"])
+		if server == "" {
+			log.WithField(logFieldSecret, s.Name).Warnf("skipping cluster secret '%s' without server", s.Name)
+			continue
+		}
+		var config v1alpha1.ClusterConfig
+		if err := json.Unmarshal(s.Data["config"], &config); err != nil {
+			log.WithField(logFieldSecret, s.Name).Warnf("skipping cluster secret '%s' with invalid config: %v", s.Name, err)
+			continue
+		}
+		clusters = append(clusters, Cluster{
+			Name:   string(s.Data["name"]),
+			Server: server,
+			Config: config,
+		})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Server < clusters[j].Server
+	})
+	return clusters, nil
+}
+
+// ListLegacyRepoSecrets returns the secrets labeled as repository secrets, which hold legacy repository settings
+func (mgr *SettingsManager) ListLegacyRepoSecrets() ([]*apiv1.Secret, error) {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector := labels.NewSelector()
+	req, err := labels.NewRequirement(common.LabelKeySecretType, selection.Equals, []string{"repository"})
+	if err != nil {
+		return nil, err
+	}
+	labelSelector = labelSelector.Add(*req)
+	return mgr.secrets.Secrets(mgr.namespace).List(labelSelector)
+}
+
+// MigrateLegacyRepoSettings migrates legacy (v0.10 and below) repo secrets into the v0.11 configmap
+func (mgr *SettingsManager) MigrateLegacyRepoSettings(settings *ArgoCDSettings) error {
+	repoSecrets, err := mgr.ListLegacyRepoSecrets()
+	if err != nil {
+		return err
+	}
+	settings.Repositories = make([]RepoCredentials, len(repoSecrets))
+	for i, s := range repoSecrets {
+		_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(s)
+		if err != nil {
+			return err
+		}
+		cred := RepoCredentials{URL: string(s.Data["repository"])}
+		if username, ok := s.Data["username"]; ok && string(username) != "" {
+			cred.UsernameSecret = &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
+				Key:                  "username",
+			}
+		}
+		if password, ok := s.Data["password"]; ok && string(password) != "" {
+			cred.PasswordSecret = &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
+				Key:                  "password",
+			}
+		}
+		if sshPrivateKey, ok := s.Data["sshPrivateKey"]; ok && string(sshPrivateKey) != "" {
+			cred.SSHPrivateKeySecret = &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
+				Key:                  "sshPrivateKey",
+			}
+		}
+		settings.Repositories[i] = cred
+	}
+	return nil
+}
+
+// initializeWithRetries starts the informers and waits for their caches to sync. Failed attempts are retried with an
+// exponential backoff until the attempts are exhausted or the context of the settings manager is cancelled. It must
+// be called without holding the mutex, so other callers aren't blocked while it waits.
+func (mgr *SettingsManager) initializeWithRetries() error {
+	delay := cacheSyncBackoff.Duration
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithCancel(mgr.ctx)
+		err := mgr.initialize(ctx)
+		if err == nil {
+			mgr.mutex.Lock()
+			mgr.initContextCancel = cancel
+			mgr.mutex.Unlock()
+			return nil
+		}
+		// stop the informers of the failed attempt
+		cancel()
+		if attempt >= cacheSyncBackoff.Steps {
+			return err
+		}
+		log.WithField(logFieldNamespace, mgr.namespace).Warnf("Settings cache sync attempt %d/%d failed, retrying in %v: %v", attempt, cacheSyncBackoff.Steps, delay, err)
+		select {
+		case <-mgr.ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = time.Duration(float64(delay) * cacheSyncBackoff.Factor)
+	}
+}
+
+func (mgr *SettingsManager) initialize(ctx context.Context) error {
+	cmInformer, secretsInformer := startInformers(ctx, mgr.clientset, mgr.namespace, mgr.configMapName)
+	informers := []cache.SharedIndexInformer{cmInformer, secretsInformer}
+	var fallbackCMInformer, fallbackSecretsInformer cache.SharedIndexInformer
+	if mgr.fallbackNamespace != "" {
+		fallbackCMInformer, fallbackSecretsInformer = startInformers(ctx, mgr.clientset, mgr.fallbackNamespace, mgr.configMapName)
+		informers = append(informers, fallbackCMInformer, fallbackSecretsInformer)
+	}
+
+	hasSynced := make([]cache.InformerSynced, len(informers))
+	for i := range informers {
+		hasSynced[i] = informers[i].HasSynced
+	}
+	syncCtx, syncCancel := context.WithTimeout(ctx, cacheSyncTimeout)
+	defer syncCancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), hasSynced...) {
+		return fmt.Errorf("Timed out waiting for settings cache to sync")
+	}
+	log.Info("Configmap/secret informer synced")
+
+	handler := mgr.newEventHandler(time.Now())
+	for _, informer := range informers {
+		informer.AddEventHandler(handler)
+	}
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.secrets = v1listers.NewSecretLister(secretsInformer.GetIndexer())
+	mgr.configmaps = v1listers.NewConfigMapLister(cmInformer.GetIndexer())
+	if mgr.fallbackNamespace != "" {
+		mgr.fallbackSecrets = v1listers.NewSecretLister(fallbackSecretsInformer.GetIndexer())
+		mgr.fallbackConfigmaps = v1listers.NewConfigMapLister(fallbackCMInformer.GetIndexer())
+	}
+	return nil
+}
+
+// startInformers starts the informers of the ConfigMap with the given name and the secrets of the given namespace
+func startInformers(ctx context.Context, clientset kubernetes.Interface, namespace string, configMapName string) (cache.SharedIndexInformer, cache.SharedIndexInformer) {
+	secretsInformer := v1.NewSecretInformer(clientset, namespace, 3*time.Minute, cache.Indexers{})
+
+	log.Infof("Starting configmap/secret informers in namespace %s", namespace)
+	cmInformer := startConfigMapInformer(ctx, clientset, namespace, configMapName)
+	go func() {
+		secretsInformer.Run(ctx.Done())
+		log.Info("secrets informer cancelled")
+	}()
+	return cmInformer, secretsInformer
+}
+
+// startConfigMapInformer starts the informer of the ConfigMap with the given name of the given namespace
+func startConfigMapInformer(ctx context.Context, clientset kubernetes.Interface, namespace string, name string) cache.SharedIndexInformer {
+	tweakConfigMap := func(options *metav1.ListOptions) {
+		cmFieldSelector := fields.ParseSelectorOrDie(fmt.Sprintf("metadata.name=%s", name))
+		options.FieldSelector = cmFieldSelector.String()
+	}
+	cmInformer := v1.NewFilteredConfigMapInformer(clientset, namespace, 3*time.Minute, cache.Indexers{}, tweakConfigMap)
+	go func() {
+		cmInformer.Run(ctx.Done())
+		log.Infof("configmap %s informer cancelled", name)
+	}()
+	return cmInformer
+}
+
+func (mgr *SettingsManager) tryNotify() {
+	newSettings, err := mgr.GetSettings()
+	if err != nil {
+		log.WithField(logFieldNamespace, mgr.namespace).Warnf("Unable to parse updated settings: %v", err)
+	} else {
+		mgr.notifySubscribers(newSettings)
+	}
+}
+
+// newEventHandler returns the informer event handler which notifies subscribers about objects created after the
+// given time and about updated objects
+func (mgr *SettingsManager) newEventHandler(now time.Time) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			mgr.invalidateCache()
+			if metaObj, ok := obj.(metav1.Object); ok {
+				if metaObj.GetCreationTimestamp().After(now) {
+					mgr.tryNotify()
+				}
+			}
+
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			mgr.invalidateCache()
+			oldVersion, oldOk := resourceVersion(oldObj)
+			newVersion, newOk := resourceVersion(newObj)
+			if !oldOk || !newOk {
+				log.Debugf("Skipping settings update event of unsupported objects %T/%T", oldObj, newObj)
+				return
+			}
+			if oldVersion != newVersion {
+				mgr.tryNotify()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			mgr.invalidateCache()
+		},
+	}
+}
+
+// resourceVersion returns the resource version of the given object. Objects which do not implement metav1.Common are
+// inspected using meta.Accessor.
+func resourceVersion(obj interface{}) (string, bool) {
+	if metaCommon, ok := obj.(metav1.Common); ok {
+		return metaCommon.GetResourceVersion(), true
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", false
+	}
+	return accessor.GetResourceVersion(), true
+}
+
+func (mgr *SettingsManager) ensureSynced(forceResync bool) error {
+	mgr.mutex.Lock()
+	if initializing := mgr.initializing; initializing != nil {
+		// another caller is (re)initializing the informers, which also satisfies a requested resync
+		mgr.mutex.Unlock()
+		<-initializing
+		mgr.mutex.Lock()
+		defer mgr.mutex.Unlock()
+		return mgr.initErr
+	}
+	if !forceResync && mgr.secrets != nil && mgr.configmaps != nil {
+		mgr.mutex.Unlock()
+		return nil
+	}
+
+	if mgr.initContextCancel != nil {
+		mgr.initContextCancel()
+		mgr.initContextCancel = nil
+	}
+	initializing := make(chan struct{})
+	mgr.initializing = initializing
+	mgr.mutex.Unlock()
+
+	err := mgr.initializeWithRetries()
+
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.initErr = err
+	mgr.initializing = nil
+	close(initializing)
+	if err != nil {
+		return err
+	}
+	mgr.invalidateCache()
+	// objects which existed before the informers started don't trigger notifications, so notify subscribers
+	// about the initially loaded settings once the caches are synced for the first time
+	mgr.initialNotifyOnce.Do(func() {
+		go mgr.notifyInitialSettings()
+	})
+	return nil
+}
+
+// notifyInitialSettings notifies subscribers about the current settings unless they were already notified about
+// identical settings
+func (mgr *SettingsManager) notifyInitialSettings() {
+	newSettings, err := mgr.GetSettings()
+	if err != nil {
+		log.WithField(logFieldNamespace, mgr.namespace).Warnf("Unable to parse initial settings: %v", err)
+		return
+	}
+	mgr.mutex.Lock()
+	duplicate := reflect.DeepEqual(mgr.lastNotified, newSettings)
+	mgr.mutex.Unlock()
+	if !duplicate {
+		mgr.notifySubscribers(newSettings)
+	}
+}
+
+// mergeDexConnectors appends the connectors configured in dex.config.connector.* keys to the connectors of the given
+// dex config. Connectors without an id are identified by the key suffix. Duplicate connector ids are rejected.
+func mergeDexConnectors(dexConfig string, cmData map[string]string) (string, error) {
+	var connectorKeys []string
+	for k := range cmData {
+		if strings.HasPrefix(k, dexConfigConnectorKeyPrefix) {
+			connectorKeys = append(connectorKeys, k)
+		}
+	}
+	if len(connectorKeys) == 0 {
+		return dexConfig, nil
+	}
+	sort.Strings(connectorKeys)
+
+	var dexCfg map[string]interface{}
+	if err := yaml.Unmarshal([]byte(dexConfig), &dexCfg); err != nil {
+		return "", fmt.Errorf("invalid %s: %v", settingDexConfigKey, err)
+	}
+	if dexCfg == nil {
+		dexCfg = make(map[string]interface{})
+	}
+	var connectors []interface{}
+	if existing, ok := dexCfg["connectors"]; ok && existing != nil {
+		if connectors, ok = existing.([]interface{}); !ok {
+			return "", fmt.Errorf("invalid %s: connectors must be a list", settingDexConfigKey)
+		}
+	}
+	connectorIDs := make(map[string]bool)
+	for _, c := range connectors {
+		if connector, ok := c.(map[string]interface{}); ok {
+			if id, ok := connector["id"].(string); ok {
+				connectorIDs[id] = true
+			}
+		}
+	}
+	for _, k := range connectorKeys {
+		var connector map[string]interface{}
+		if err := yaml.Unmarshal([]byte(cmData[k]), &connector); err != nil {
+			return "", fmt.Errorf("invalid %s: %v", k, err)
+		}
+		if connector == nil {
+			return "", fmt.Errorf("invalid %s: connector must not be empty", k)
+		}
+		id, _ := connector["id"].(string)
+		if id == "" {
+			id = strings.TrimPrefix(k, dexConfigConnectorKeyPrefix)
+			connector["id"] = id
+		}
+		if connectorIDs[id] {
+			return "", fmt.Errorf("duplicate dex connector id '%s' in %s", id, k)
+		}
+		connectorIDs[id] = true
+		connectors = append(connectors, connector)
+	}
+	dexCfg["connectors"] = connectors
+	merged, err := yaml.Marshal(dexCfg)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// splitDexConnectors removes the connectors configured in dex.config.connector.* keys from the given dex config, so
+// that the connectors merged by mergeDexConnectors are not persisted into dex.config
+func splitDexConnectors(dexConfig string, cmData map[string]string) (string, error) {
+	connectorIDs := make(map[string]bool)
+	for k, v := range cmData {
+		if !strings.HasPrefix(k, dexConfigConnectorKeyPrefix) {
+			continue
+		}
+		var connector map[string]interface{}
+		if err := yaml.Unmarshal([]byte(v), &connector); err != nil {
+			return "", fmt.Errorf("invalid %s: %v", k, err)
+		}
+		id, _ := connector["id"].(string)
+		if id == "" {
+			id = strings.TrimPrefix(k, dexConfigConnectorKeyPrefix)
+		}
+		connectorIDs[id] = true
+	}
+	if len(connectorIDs) == 0 || dexConfig == "" {
+		return dexConfig, nil
+	}
+
+	var dexCfg map[string]interface{}
+	if err := yaml.Unmarshal([]byte(dexConfig), &dexCfg); err != nil {
+		return "", fmt.Errorf("invalid %s: %v", settingDexConfigKey, err)
+	}
+	existing, ok := dexCfg["connectors"]
+	if !ok || existing == nil {
+		return dexConfig, nil
+	}
+	connectors, ok := existing.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid %s: connectors must be a list", settingDexConfigKey)
+	}
+	var baseConnectors []interface{}
+	for _, c := range connectors {
+		if connector, ok := c.(map[string]interface{}); ok {
+			if id, ok := connector["id"].(string); ok && connectorIDs[id] {
+				continue
+			}
+		}
+		baseConnectors = append(baseConnectors, c)
+	}
+	if len(baseConnectors) > 0 {
+		dexCfg["connectors"] = baseConnectors
+	} else {
+		delete(dexCfg, "connectors")
+	}
+	if len(dexCfg) == 0 {
+		return "", nil
+	}
+	base, err := yaml.Marshal(dexCfg)
+	if err != nil {
+		return "", err
+	}
+	return string(base), nil
+}
+
+// yamlEqual returns whether the given YAML documents unmarshal into the same objects
+func yamlEqual(a, b string) bool {
+	var objA, objB interface{}
+	if err := yaml.Unmarshal([]byte(a), &objA); err != nil {
+		return false
+	}
+	if err := yaml.Unmarshal([]byte(b), &objB); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(objA, objB)
+}
+
+func updateSettingsFromConfigMap(settings *ArgoCDSettings, argoCDCM *apiv1.ConfigMap) error {
+	var errors []error
+	settings.DexConfig = argoCDCM.Data[settingDexConfigKey]
+	if dexConfig, err := mergeDexConnectors(settings.DexConfig, argoCDCM.Data); err != nil {
+		errors = append(errors, err)
+	} else {
+		settings.DexConfig = dexConfig
+	}
+	settings.OIDCConfigRAW = argoCDCM.Data[settingsOIDCConfigKey]
+	settings.URL = argoCDCM.Data[settingURLKey]
+	settings.OIDCCacheExpirationRAW = argoCDCM.Data[settingsOIDCCacheExpirationKey]
+	settings.StylesURL = argoCDCM.Data[settingUICSSURLKey]
+	settings.StylesContent = argoCDCM.Data[settingUICSSContentKey]
+	settings.HelmDefaultValueFilesRAW = argoCDCM.Data[helmDefaultValueFilesKey]
+	settings.HelmValueFileSchemesRAW = argoCDCM.Data[helmValueFileSchemesKey]
+	settings.ApplicationNamespacesRAW = argoCDCM.Data[applicationNamespacesKey]
+	settings.JWTSigningAlgorithmRAW = argoCDCM.Data[settingJWTSigningAlgorithmKey]
+	settings.AnonymousUserEnabledRAW = argoCDCM.Data[settingAnonymousUserEnabledKey]
+	settings.GitRequestTimeoutRAW = argoCDCM.Data[gitRequestTimeoutKey]
+	settings.ReconciliationTimeoutRAW = argoCDCM.Data[reconciliationTimeoutKey]
+	settings.GitSubmoduleEnabledRAW = argoCDCM.Data[gitSubmoduleEnabledKey]
+	settings.RespectRBACRAW = argoCDCM.Data[respectRBACKey]
+	settings.BasePathRAW = argoCDCM.Data[settingServerRootPathKey]
+	if settings.BasePathRAW == "" {
+		settings.BasePathRAW = argoCDCM.Data[settingServerBaseHRefKey]
+	}
+	settings.ExtraHTTPHeadersRAW = argoCDCM.Data[settingServerExtraHeadersKey]
+	settings.ControllerReplicasRAW = argoCDCM.Data[controllerReplicasKey]
+	settings.MaxConcurrentSyncsRAW = argoCDCM.Data[maxConcurrentSyncsKey]
+	settings.AdminDisabled = !getBool(settingAdminEnabledKey, argoCDCM.Data[settingAdminEnabledKey], !settings.AdminDisabled)
+	settings.Insecure = getBool(settingServerInsecureKey, argoCDCM.Data[settingServerInsecureKey], settings.Insecure)
+	repositoriesStr := argoCDCM.Data[repositoriesKey]
+	repositoryCredentialsStr := argoCDCM.Data[repositoryCredentialsKey]
+	if repositoriesStr != "" {
+		repositories := make([]RepoCredentials, 0)
+		err := yaml.Unmarshal([]byte(repositoriesStr), &repositories)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			substituteRepoEnvVars(repositories)
+			settings.Repositories = repositories
+		}
+	}
+	if repositoryCredentialsStr != "" {
+		repositoryCredentials := make([]RepoCredentials, 0)
+		err := yaml.Unmarshal([]byte(repositoryCredentialsStr), &repositoryCredentials)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			substituteRepoEnvVars(repositoryCredentials)
+			settings.RepositoryCredentials = repositoryCredentials
+		}
+	}
+	helmRepositoriesStr := argoCDCM.Data[helmRepositoriesKey]
+	if helmRepositoriesStr != "" {
+		helmRepositories := make([]HelmRepoCredentials, 0)
+		err := yaml.Unmarshal([]byte(helmRepositoriesStr), &helmRepositories)
+		if err != nil {
+			errors = append(errors, err)
+		} else {
+			settings.HelmRepositories = helmRepositories
+		}
+	}
+	if alg := settings.JWTSigningAlgorithmRAW; alg != "" && !containsString(jwtSigningAlgorithms, alg) {
+		errors = append(errors, fmt.Errorf("invalid %s '%s', must be one of %v", settingJWTSigningAlgorithmKey, alg, jwtSigningAlgorithms))
+	}
+
+	if len(errors) > 0 {
+		return errors[0]
+	}
+	return nil
+}
+
+// updateSettingsFromSecret transfers settings from a Kubernetes secret into an ArgoCDSettings struct.
+func updateSettingsFromSecret(settings *ArgoCDSettings, argoCDSecret *apiv1.Secret) error {
+	var errs []error
+	adminPasswordHash, ok := argoCDSecret.Data[settingAdminPasswordHashKey]
+	if ok {
+		settings.AdminPasswordHash = string(adminPasswordHash)
+	} else {
+		errs = append(errs, missingSecretKeyError(settingAdminPasswordHashKey))
+	}
+	adminPasswordMtimeBytes, ok := argoCDSecret.Data[settingAdminPasswordMtimeKey]
+	if ok {
+		if adminPasswordMtime, err := time.Parse(time.RFC3339, string(adminPasswordMtimeBytes)); err == nil {
+			settings.AdminPasswordMtime = adminPasswordMtime
+		}
+	}
+	secretKey, ok := argoCDSecret.Data[settingServerSignatureKey]
+	if ok {
+		settings.ServerSignature = secretKey
+	} else {
+		errs = append(errs, missingSecretKeyError(settingServerSignatureKey))
+	}
+	if previousSecretKey := argoCDSecret.Data[settingServerSignaturePreviousKey]; len(previousSecretKey) > 0 {
+		settings.PreviousServerSignature = previousSecretKey
+	}
+	if rotationTimeBytes, ok := argoCDSecret.Data[settingServerSignatureRotationTimeKey]; ok {
+		if rotationTime, err := time.Parse(time.RFC3339, string(rotationTimeBytes)); err == nil {
+			settings.ServerSignatureRotationTime = rotationTime
+		}
+	}
+	if githubWebhookSecret := argoCDSecret.Data[settingsWebhookGitHubSecretKey]; len(githubWebhookSecret) > 0 {
+		settings.WebhookGitHubSecret = string(githubWebhookSecret)
+	}
+	if gitlabWebhookSecret := argoCDSecret.Data[settingsWebhookGitLabSecretKey]; len(gitlabWebhookSecret) > 0 {
+		settings.WebhookGitLabSecret = string(gitlabWebhookSecret)
+	}
+	if bitbucketWebhookUUID := argoCDSecret.Data[settingsWebhookBitbucketUUIDKey]; len(bitbucketWebhookUUID) > 0 {
+		settings.WebhookBitbucketUUID = string(bitbucketWebhookUUID)
+	}
+	if bitbucketServerWebhookSecret := argoCDSecret.Data[settingsWebhookBitbucketServerSecretKey]; len(bitbucketServerWebhookSecret) > 0 {
+		settings.WebhookBitbucketServerSecret = string(bitbucketServerWebhookSecret)
+	}
+
+	serverCert, certOk := argoCDSecret.Data[settingServerCertificate]
+	serverKey, keyOk := argoCDSecret.Data[settingServerPrivateKey]
+	if certOk && keyOk {
+		cert, err := tls.X509KeyPair(serverCert, serverKey)
+		if err != nil {
+			errs = append(errs, &incompleteSettingsError{message: fmt.Sprintf("invalid x509 key pair %s/%s in secret: %s", settingServerCertificate, settingServerPrivateKey, err)})
+		} else {
+			settings.Certificate = &cert
+		}
+	}
+	secretValues := make(map[string]string, len(argoCDSecret.Data))
+	for k, v := range argoCDSecret.Data {
+		secretValues[k] = string(v)
+	}
+	settings.Secrets = secretValues
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// SaveSettings serializes ArgoCDSettings and upserts it into K8s secret/configmap
+func (mgr *SettingsManager) SaveSettings(settings *ArgoCDSettings) error {
+	err := mgr.ensureSynced(false)
+	if err != nil {
+		return err
+	}
+
+	// Upsert the config data
+	argoCDCM, createCM, err := mgr.getConfigMapForUpsert(false)
+	for attempt := 1; ; attempt++ {
+		if err != nil {
+			return err
+		}
+		originalKeys := make(map[string]bool)
+		for k := range argoCDCM.Data {
+			originalKeys[k] = true
+		}
+		err = applySettingsToConfigMap(settings, argoCDCM)
+		if err != nil {
+			return err
+		}
+		err = mgr.dropFallbackConfigMapKeys(originalKeys, argoCDCM.Data)
+		if err != nil {
+			return err
+		}
+		if createCM {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Create(argoCDCM)
+		} else {
+			_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Update(argoCDCM)
+		}
+		if !(apierr.IsConflict(err) || apierr.IsAlreadyExists(err)) || attempt >= saveSettingsAttempts {
+			break
+		}
+		// the ConfigMap was created or updated concurrently, re-apply the managed keys to the latest version
+		log.Warnf("conflict when saving %s, retrying", mgr.configMapName)
+		argoCDCM, createCM, err = mgr.getConfigMapForUpsert(true)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Upsert the secret data. Ensure we do not delete any extra keys which user may have added
+	argoCDSecret, createSecret, err := mgr.getSecretForUpsert(false)
+	for attempt := 1; ; attempt++ {
+		if err != nil {
+			return err
+		}
+		originalKeys := make(map[string]bool)
+		for k := range argoCDSecret.Data {
+			originalKeys[k] = true
+		}
+		applySettingsToSecret(settings, argoCDSecret)
+		err = mgr.dropFallbackSecretKeys(originalKeys, argoCDSecret.Data)
+		if err != nil {
+			return err
+		}
+		if createSecret {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Create(argoCDSecret)
+		} else {
+			_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(argoCDSecret)
+		}
+		if !(apierr.IsConflict(err) || apierr.IsAlreadyExists(err)) || attempt >= saveSettingsAttempts {
+			break
+		}
+		log.Warnf("conflict when saving %s, retrying", mgr.secretName)
+		argoCDSecret, createSecret, err = mgr.getSecretForUpsert(true)
+	}
+	if err != nil {
+		return err
+	}
+	return mgr.ResyncInformers()
+}
+
+// SaveSettingsDryRun returns the changes SaveSettings would apply to the ConfigMap and the Secret without
+// modifying them. Secret values are masked.
+func (mgr *SettingsManager) SaveSettingsDryRun(settings *ArgoCDSettings) (cmDiff, secretDiff string, err error) {
+	err = mgr.ensureSynced(false)
+	if err != nil {
+		return "", "", err
+	}
+
+	argoCDCM, _, err := mgr.getConfigMapForUpsert(false)
+	if err != nil {
+		return "", "", err
+	}
+	oldCMData := make(map[string]string)
+	originalCMKeys := make(map[string]bool)
+	for k, v := range argoCDCM.Data {
+		oldCMData[k] = v
+		originalCMKeys[k] = true
+	}
+	err = applySettingsToConfigMap(settings, argoCDCM)
+	if err != nil {
+		return "", "", err
+	}
+	err = mgr.dropFallbackConfigMapKeys(originalCMKeys, argoCDCM.Data)
+	if err != nil {
+		return "", "", err
+	}
+
+	argoCDSecret, _, err := mgr.getSecretForUpsert(false)
+	if err != nil {
+		return "", "", err
+	}
+	oldSecretData := make(map[string]string)
+	originalSecretKeys := make(map[string]bool)
+	for k, v := range argoCDSecret.Data {
+		oldSecretData[k] = string(v)
+		originalSecretKeys[k] = true
+	}
+	applySettingsToSecret(settings, argoCDSecret)
+	err = mgr.dropFallbackSecretKeys(originalSecretKeys, argoCDSecret.Data)
+	if err != nil {
+		return "", "", err
+	}
+	newSecretData := make(map[string]string)
+	for k, v := range argoCDSecret.Data {
+		newSecretData[k] = string(v)
+	}
+
+	return diffData(oldCMData, argoCDCM.Data, false), diffData(oldSecretData, newSecretData, true), nil
+}
+
+// getConfigMapForUpsert returns a copy of the ArgoCDConfigMap, or a new ConfigMap which needs to be created.
+// The latest version is read from the API server instead of the informer cache if latest is true.
+func (mgr *SettingsManager) getConfigMapForUpsert(latest bool) (*apiv1.ConfigMap, bool, error) {
+	var argoCDCM *apiv1.ConfigMap
+	var err error
+	if latest {
+		argoCDCM, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Get(mgr.configMapName, metav1.GetOptions{})
+	} else {
+		argoCDCM, err = mgr.configmaps.ConfigMaps(mgr.namespace).Get(mgr.configMapName)
+	}
+	createCM := false
+	if err != nil {
+		if !apierr.IsNotFound(err) {
+			return nil, false, err
+		}
+		if !latest {
+			// the informer cache might be stale, don't drop keys another writer has added in the meantime
+			return mgr.getConfigMapForUpsert(true)
+		}
+		argoCDCM = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: mgr.configMapName,
+			},
+		}
+		createCM = true
+	} else {
+		argoCDCM = argoCDCM.DeepCopy()
+	}
+	if argoCDCM.Data == nil {
+		argoCDCM.Data = make(map[string]string)
+	}
+	return argoCDCM, createCM, nil
+}
+
+// getSecretForUpsert returns a copy of the ArgoCDSecret, or a new Secret which needs to be created.
+// The latest version is read from the API server instead of the informer cache if latest is true.
+func (mgr *SettingsManager) getSecretForUpsert(latest bool) (*apiv1.Secret, bool, error) {
+	var argoCDSecret *apiv1.Secret
+	var err error
+	if latest {
+		argoCDSecret, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Get(mgr.secretName, metav1.GetOptions{})
+	} else {
+		argoCDSecret, err = mgr.secrets.Secrets(mgr.namespace).Get(mgr.secretName)
+	}
+	createSecret := false
+	if err != nil {
+		if !apierr.IsNotFound(err) {
+			return nil, false, err
+		}
+		if !latest {
+			return mgr.getSecretForUpsert(true)
+		}
+		argoCDSecret = &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: mgr.secretName,
+			},
+			Data: make(map[string][]byte),
+		}
+		createSecret = true
+	} else {
+		argoCDSecret = argoCDSecret.DeepCopy()
+	}
+	if argoCDSecret.Data == nil {
+		argoCDSecret.Data = make(map[string][]byte)
+	}
+	return argoCDSecret, createSecret, nil
+}
+
+// applySettingsToConfigMap writes the ConfigMap keys managed by SaveSettings
+func applySettingsToConfigMap(settings *ArgoCDSettings, argoCDCM *apiv1.ConfigMap) error {
+	if settings.URL != "" {
+		argoCDCM.Data[settingURLKey] = settings.URL
+	} else {
+		delete(argoCDCM.Data, settingURLKey)
+	}
+	dexConfig, err := splitDexConnectors(settings.DexConfig, argoCDCM.Data)
+	if err != nil {
+		return err
+	}
+	if dexConfig == "" {
+		delete(argoCDCM.Data, settingDexConfigKey)
+	} else if !yamlEqual(argoCDCM.Data[settingDexConfigKey], dexConfig) {
+		// keep the formatting of an unchanged dex.config
+		argoCDCM.Data[settingDexConfigKey] = dexConfig
+	}
+	if settings.OIDCConfigRAW != "" {
+		argoCDCM.Data[settingsOIDCConfigKey] = settings.OIDCConfigRAW
+	} else {
+		delete(argoCDCM.Data, settingsOIDCConfigKey)
+	}
+	applyBool(argoCDCM.Data, settingAdminEnabledKey, !settings.AdminDisabled, true)
+	applyBool(argoCDCM.Data, settingServerInsecureKey, settings.Insecure, false)
+	if len(settings.Repositories) > 0 {
+		yamlStr, err := yaml.Marshal(repoCredentialsTemplates(settings.Repositories))
+		if err != nil {
+			return err
+		}
+		argoCDCM.Data[repositoriesKey] = string(yamlStr)
+	} else {
+		delete(argoCDCM.Data, repositoriesKey)
+	}
+	if len(settings.RepositoryCredentials) > 0 {
+		yamlStr, err := yaml.Marshal(repoCredentialsTemplates(settings.RepositoryCredentials))
+		if err != nil {
+			return err
+		}
+		argoCDCM.Data[repositoryCredentialsKey] = string(yamlStr)
+	} else {
+		delete(argoCDCM.Data, repositoryCredentialsKey)
+	}
+	if len(settings.HelmRepositories) > 0 {
+		yamlStr, err := yaml.Marshal(settings.HelmRepositories)
+		if err != nil {
+			return err
+		}
+		argoCDCM.Data[helmRepositoriesKey] = string(yamlStr)
+	} else {
+		delete(argoCDCM.Data, helmRepositoriesKey)
+	}
+	return nil
+}
+
+// applySettingsToSecret writes the Secret keys managed by SaveSettings
+func applySettingsToSecret(settings *ArgoCDSettings, argoCDSecret *apiv1.Secret) {
+	argoCDSecret.Data[settingServerSignatureKey] = settings.ServerSignature
+	if len(settings.PreviousServerSignature) > 0 {
+		argoCDSecret.Data[settingServerSignaturePreviousKey] = settings.PreviousServerSignature
+	} else {
+		delete(argoCDSecret.Data, settingServerSignaturePreviousKey)
+	}
+	if !settings.ServerSignatureRotationTime.IsZero() {
+		argoCDSecret.Data[settingServerSignatureRotationTimeKey] = []byte(settings.ServerSignatureRotationTime.Format(time.RFC3339))
+	} else {
+		delete(argoCDSecret.Data, settingServerSignatureRotationTimeKey)
+	}
+	argoCDSecret.Data[settingAdminPasswordHashKey] = []byte(settings.AdminPasswordHash)
+	argoCDSecret.Data[settingAdminPasswordMtimeKey] = []byte(settings.AdminPasswordMtime.Format(time.RFC3339))
+	if settings.WebhookGitHubSecret != "" {
+		argoCDSecret.Data[settingsWebhookGitHubSecretKey] = []byte(settings.WebhookGitHubSecret)
+	}
+	if settings.WebhookGitLabSecret != "" {
+		argoCDSecret.Data[settingsWebhookGitLabSecretKey] = []byte(settings.WebhookGitLabSecret)
+	}
+	if settings.WebhookBitbucketUUID != "" {
+		argoCDSecret.Data[settingsWebhookBitbucketUUIDKey] = []byte(settings.WebhookBitbucketUUID)
+	}
+	if settings.WebhookBitbucketServerSecret != "" {
+		argoCDSecret.Data[settingsWebhookBitbucketServerSecretKey] = []byte(settings.WebhookBitbucketServerSecret)
+	}
+	if settings.Certificate != nil {
+		cert, key := tlsutil.EncodeX509KeyPair(*settings.Certificate)
+		argoCDSecret.Data[settingServerCertificate] = cert
+		argoCDSecret.Data[settingServerPrivateKey] = key
+	} else {
+		delete(argoCDSecret.Data, settingServerCertificate)
+		delete(argoCDSecret.Data, settingServerPrivateKey)
+	}
+}
+
+// diffData returns the added (+), removed (-) and changed (~) keys of the given data, one key per line sorted by
+// key. Values are masked if mask is true.
+func diffData(oldData, newData map[string]string, mask bool) string {
+	format := func(value string) string {
+		if mask {
+			return redactedValue
+		}
+		return strconv.Quote(value)
+	}
+	keys := make([]string, 0)
+	for k := range oldData {
+		keys = append(keys, k)
+	}
+	for k := range newData {
+		if _, ok := oldData[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var lines []string
+	for _, k := range keys {
+		oldValue, oldOk := oldData[k]
+		newValue, newOk := newData[k]
+		switch {
+		case !oldOk:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", k, format(newValue)))
+		case !newOk:
+			lines = append(lines, fmt.Sprintf("- %s: %s", k, format(oldValue)))
+		case oldValue != newValue:
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", k, format(oldValue), format(newValue)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RotateServerSignature generates a new server signature and keeps the current one as the previous signature,
+// so that tokens signed with it remain valid during the rotation grace period
+func (mgr *SettingsManager) RotateServerSignature() error {
+	settings, err := mgr.GetSettings()
+	if err != nil {
+		return err
+	}
+	signature, err := util.MakeSignature(32)
+	if err != nil {
+		return err
+	}
+	settings.PreviousServerSignature = settings.ServerSignature
+	settings.ServerSignature = signature
+	settings.ServerSignatureRotationTime = time.Now().UTC()
+	log.Info("Rotated server signature")
+	return mgr.SaveSettings(settings)
+}
+
+// NewSettingsManager generates a new SettingsManager pointer and returns it
+func NewSettingsManager(ctx context.Context, clientset kubernetes.Interface, namespace string, opts ...SettingsManagerOpts) *SettingsManager {
+
+	mgr := &SettingsManager{
+		ctx:           ctx,
+		clientset:     clientset,
+		namespace:     namespace,
+		configMapName: common.ArgoCDConfigMapName,
+		secretName:    common.ArgoCDSecretName,
+		mutex:         &sync.Mutex{},
+	}
+	for _, opt := range opts {
+		opt(mgr)
+	}
+
+	return mgr
+}
+
+// SettingsManagerOpts configures optional aspects of a settings manager
+type SettingsManagerOpts func(mgr *SettingsManager)
+
+// WithConfigMapName overrides the name of the ArgoCDConfigMap
+func WithConfigMapName(name string) SettingsManagerOpts {
+	return func(mgr *SettingsManager) {
+		mgr.configMapName = name
+	}
+}
+
+// WithSecretName overrides the name of the ArgoCDSecret
+func WithSecretName(name string) SettingsManagerOpts {
+	return func(mgr *SettingsManager) {
+		mgr.secretName = name
+	}
+}
+
+// NewSettingsManagerWithFallback creates a settings manager which reads the settings from the primary namespace and
+// falls back to the settings of the fallback namespace for missing objects and keys. The fallback settings are
+// read-only: saving the settings only writes keys to the primary namespace which are not inherited unchanged.
+func NewSettingsManagerWithFallback(ctx context.Context, clientset kubernetes.Interface, primaryNamespace, fallbackNamespace string, opts ...SettingsManagerOpts) *SettingsManager {
+	mgr := NewSettingsManager(ctx, clientset, primaryNamespace, opts...)
+	if fallbackNamespace != primaryNamespace {
+		mgr.fallbackNamespace = fallbackNamespace
+	}
+	return mgr
+}
+
+// Namespace returns the namespace of the settings
+func (mgr *SettingsManager) Namespace() string {
+	return mgr.namespace
+}
+
+// Namespaces returns the namespaces whose settings are watched: the primary namespace followed by the fallback
+// namespace if configured
+func (mgr *SettingsManager) Namespaces() []string {
+	namespaces := []string{mgr.namespace}
+	if mgr.fallbackNamespace != "" {
+		namespaces = append(namespaces, mgr.fallbackNamespace)
+	}
+	return namespaces
+}
+
+func (mgr *SettingsManager) ResyncInformers() error {
+	return mgr.ensureSynced(true)
+}
+
+// IsInsecure returns whether the API server intentionally runs without TLS
+func (a *ArgoCDSettings) IsInsecure() bool {
+	return a.Insecure
+}
+
+// AnonymousUserEnabled returns whether unauthenticated requests are granted the default role. Defaults to false.
+func (a *ArgoCDSettings) AnonymousUserEnabled() bool {
+	return getBool(settingAnonymousUserEnabledKey, a.AnonymousUserEnabledRAW, false)
+}
+
+// GitRequestTimeout returns the duration after which Git requests to repositories time out. Defaults to 15s.
+func (a *ArgoCDSettings) GitRequestTimeout() time.Duration {
+	if a.GitRequestTimeoutRAW == "" {
+		return defaultGitRequestTimeout
+	}
+	timeout, err := time.ParseDuration(a.GitRequestTimeoutRAW)
+	if err != nil {
+		log.WithField(logFieldSettingKey, gitRequestTimeoutKey).Warnf("invalid duration '%s' of %s, using default %v", a.GitRequestTimeoutRAW, gitRequestTimeoutKey, defaultGitRequestTimeout)
+		return defaultGitRequestTimeout
+	}
+	return timeout
+}
+
+// ReconciliationTimeout returns the interval at which applications are reconciled. Defaults to 180s, zero and
+// negative durations are rejected in favor of the default.
+func (a *ArgoCDSettings) ReconciliationTimeout() time.Duration {
+	if a.ReconciliationTimeoutRAW == "" {
+		return defaultReconciliationTimeout
+	}
+	timeout, err := time.ParseDuration(a.ReconciliationTimeoutRAW)
+	if err != nil || timeout <= 0 {
+		log.WithField(logFieldSettingKey, reconciliationTimeoutKey).Warnf("invalid duration '%s' of %s, using default %v", a.ReconciliationTimeoutRAW, reconciliationTimeoutKey, defaultReconciliationTimeout)
+		return defaultReconciliationTimeout
+	}
+	return timeout
+}
+
+// GitSubmoduleEnabled returns whether the repo server checks out Git submodules. Defaults to true.
+func (a *ArgoCDSettings) GitSubmoduleEnabled() bool {
+	return getBool(gitSubmoduleEnabledKey, a.GitSubmoduleEnabledRAW, true)
+}
+
+// RespectRBAC returns the mode in which the controller respects the RBAC of managed clusters: strict, normal or an
+// empty string if RBAC isn't respected, which is the default.
+func (a *ArgoCDSettings) RespectRBAC() string {
+	switch a.RespectRBACRAW {
+	case "", RespectRBACStrict, RespectRBACNormal:
+		return a.RespectRBACRAW
+	}
+	log.WithField(logFieldSettingKey, respectRBACKey).Warnf("invalid value '%s' of %s, must be one of %v, RBAC is not respected", a.RespectRBACRAW, respectRBACKey, []string{RespectRBACStrict, RespectRBACNormal})
+	return ""
+}
+
+// ControllerReplicas returns the number of shards the application controller runs with. Defaults to 1, values less
+// than 1 are rejected in favor of the default.
+func (a *ArgoCDSettings) ControllerReplicas() int {
+	if a.ControllerReplicasRAW == "" {
+		return 1
+	}
+	replicas, err := strconv.Atoi(strings.TrimSpace(a.ControllerReplicasRAW))
+	if err != nil || replicas < 1 {
+		log.WithField(logFieldSettingKey, controllerReplicasKey).Warnf("invalid value '%s' of %s, must be a positive integer, using 1", a.ControllerReplicasRAW, controllerReplicasKey)
+		return 1
+	}
+	return replicas
+}
+
+// MaxConcurrentSyncs returns the maximum number of sync operations running at the same time. Defaults to 0, which
+// means unlimited, negative values are rejected in favor of the default.
+func (a *ArgoCDSettings) MaxConcurrentSyncs() int {
+	if a.MaxConcurrentSyncsRAW == "" {
+		return 0
+	}
+	maxSyncs, err := strconv.Atoi(strings.TrimSpace(a.MaxConcurrentSyncsRAW))
+	if err != nil || maxSyncs < 0 {
+		log.WithField(logFieldSettingKey, maxConcurrentSyncsKey).Warnf("invalid value '%s' of %s, must be a non-negative integer, syncs are unlimited", a.MaxConcurrentSyncsRAW, maxConcurrentSyncsKey)
+		return 0
+	}
+	return maxSyncs
+}
+
+// IsAdminEnabled returns whether the built-in admin account is enabled
+func (a *ArgoCDSettings) IsAdminEnabled() bool {
+	return !a.AdminDisabled
+}
+
+// IsSSOConfigured returns whether or not single-sign-on is configured
+func (a *ArgoCDSettings) IsSSOConfigured() bool {
+	if a.IsDexConfigured() {
+		return true
+	}
+	if a.OIDCConfig() != nil {
+		return true
+	}
+	return false
+}
+
+// PKCERequired returns whether the login flow must use PKCE, which is the case if OIDC is configured with enablePKCE.
+// Logins via dex never use PKCE.
+func (a *ArgoCDSettings) PKCERequired() bool {
+	return a.OIDCConfig().IsPKCEEnabled()
+}
+
+// defaultSSOProviderName is the SSO provider name used if the provider has no name
+const defaultSSOProviderName = "SSO"
+
+// SSOProviderName returns the name of the SSO provider to show on the login button: the name of the OIDC provider,
+// the name of the first dex connector or SSO
+func (a *ArgoCDSettings) SSOProviderName() string {
+	if oidcConfig := a.OIDCConfig(); oidcConfig != nil {
+		if oidcConfig.Name != "" {
+			return oidcConfig.Name
 		}
-		if sshPrivateKey, ok := s.Data["sshPrivateKey"]; ok && string(sshPrivateKey) != "" {
-			cred.SSHPrivateKeySecret = &apiv1.SecretKeySelector{
-				LocalObjectReference: apiv1.LocalObjectReference{Name: s.Name},
-				Key:                  "sshPrivateKey",
-			}
+		return defaultSSOProviderName
+	}
+	if a.IsDexConfigured() {
+		if dexCfg, err := a.DexConfigParsed(); err == nil && len(dexCfg.Connectors) > 0 && dexCfg.Connectors[0].Name != "" {
+			return dexCfg.Connectors[0].Name
 		}
-		settings.Repositories[i] = cred
 	}
-	return nil
+	return defaultSSOProviderName
 }
 
-func (mgr *SettingsManager) initialize(ctx context.Context) error {
-	tweakConfigMap := func(options *metav1.ListOptions) {
-		cmFieldSelector := fields.ParseSelectorOrDie(fmt.Sprintf("metadata.name=%s", common.ArgoCDConfigMapName))
-		options.FieldSelector = cmFieldSelector.String()
+// Validate checks the consistency of the settings. Currently it verifies that at most one of dex.config and
+// oidc.config is configured.
+func (a *ArgoCDSettings) Validate() error {
+	if a.IsDexConfigured() && a.OIDCConfig() != nil {
+		return fmt.Errorf("both %s and %s are configured, remove one of them to choose the SSO provider", settingDexConfigKey, settingsOIDCConfigKey)
 	}
+	return nil
+}
 
-	cmInformer := v1.NewFilteredConfigMapInformer(mgr.clientset, mgr.namespace, 3*time.Minute, cache.Indexers{}, tweakConfigMap)
-	secretsInformer := v1.NewSecretInformer(mgr.clientset, mgr.namespace, 3*time.Minute, cache.Indexers{})
+// DexConfig is the parsed dex config. Only the parts of the config Argo CD inspects are modelled.
+type DexConfig struct {
+	Issuer        string            `json:"issuer,omitempty"`
+	Connectors    []DexConnector    `json:"connectors,omitempty"`
+	StaticClients []DexStaticClient `json:"staticClients,omitempty"`
+	Storage       *DexStorage       `json:"storage,omitempty"`
+}
 
-	log.Info("Starting configmap/secret informers")
-	go func() {
-		cmInformer.Run(ctx.Done())
-		log.Info("configmap informer cancelled")
-	}()
-	go func() {
-		secretsInformer.Run(ctx.Done())
-		log.Info("secrets informer cancelled")
-	}()
+// DexConnector is a dex connector to an upstream identity provider
+type DexConnector struct {
+	Type   string                 `json:"type,omitempty"`
+	ID     string                 `json:"id,omitempty"`
+	Name   string                 `json:"name,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
 
-	if !cache.WaitForCacheSync(ctx.Done(), cmInformer.HasSynced, secretsInformer.HasSynced) {
-		return fmt.Errorf("Timed out waiting for settings cache to sync")
+// DexStaticClient is an OAuth2 client registered in the dex config
+type DexStaticClient struct {
+	ID           string   `json:"id,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Secret       string   `json:"secret,omitempty"`
+	RedirectURIs []string `json:"redirectURIs,omitempty"`
+	Public       bool     `json:"public,omitempty"`
+}
+
+// DexStorage is the storage backend of dex
+type DexStorage struct {
+	Type   string                 `json:"type,omitempty"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// DexConfigParseError is returned if the dex config is malformed
+type DexConfigParseError struct {
+	Err error
+}
+
+func (e *DexConfigParseError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", settingDexConfigKey, e.Err)
+}
+
+// DexConfigParsed returns the parsed dex config. An empty config is returned if dex is not configured.
+func (a *ArgoCDSettings) DexConfigParsed() (*DexConfig, error) {
+	var dexCfg DexConfig
+	if err := yaml.Unmarshal([]byte(a.DexConfig), &dexCfg); err != nil {
+		return nil, &DexConfigParseError{Err: err}
 	}
-	log.Info("Configmap/secret informer synced")
+	return &dexCfg, nil
+}
 
-	tryNotify := func() {
-		newSettings, err := mgr.GetSettings()
-		if err != nil {
-			log.Warnf("Unable to parse updated settings: %v", err)
-		} else {
-			mgr.notifySubscribers(newSettings)
-		}
+func (a *ArgoCDSettings) IsDexConfigured() bool {
+	if a.URL == "" {
+		return false
 	}
-	now := time.Now()
-	handler := cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			if metaObj, ok := obj.(metav1.Object); ok {
-				if metaObj.GetCreationTimestamp().After(now) {
-					tryNotify()
-				}
-			}
+	var dexCfg map[string]interface{}
+	err := yaml.Unmarshal([]byte(a.DexConfig), &dexCfg)
+	if err != nil {
+		log.WithField(logFieldSettingKey, settingDexConfigKey).Warn("invalid dex yaml config")
+		return false
+	}
+	return len(dexCfg) > 0
+}
 
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			oldMeta, oldOk := oldObj.(metav1.Common)
-			newMeta, newOk := newObj.(metav1.Common)
-			if oldOk && newOk && oldMeta.GetResourceVersion() != newMeta.GetResourceVersion() {
-				tryNotify()
-			}
-		},
+// OIDCConfig returns the OIDC config. A client secret of the form $secretName:key is resolved if the settings were
+// loaded by the settings manager.
+func (a *ArgoCDSettings) OIDCConfig() *OIDCConfig {
+	oidcConfig := a.OIDCConfigWithSecrets(nil)
+	if oidcConfig == nil {
+		return nil
 	}
-	secretsInformer.AddEventHandler(handler)
-	cmInformer.AddEventHandler(handler)
-	mgr.secrets = v1listers.NewSecretLister(secretsInformer.GetIndexer())
-	mgr.configmaps = v1listers.NewConfigMapLister(cmInformer.GetIndexer())
-	return nil
+	if val, ok := a.externalSecrets[oidcConfig.ClientSecret]; ok {
+		oidcConfig.ClientSecret = val
+	}
+	return oidcConfig
 }
 
-func (mgr *SettingsManager) ensureSynced(forceResync bool) error {
-	mgr.mutex.Lock()
-	defer mgr.mutex.Unlock()
-	if !forceResync && mgr.secrets != nil && mgr.configmaps != nil {
+// OIDCConfigWithSecrets returns the OIDC config, resolving a client secret of the form $secretName:key against
+// the given secrets, in addition to the $key form resolved against argocd-secret
+func (a *ArgoCDSettings) OIDCConfigWithSecrets(secrets v1listers.SecretNamespaceLister) *OIDCConfig {
+	oidcConfig := a.parseOIDCConfig()
+	if oidcConfig == nil {
 		return nil
 	}
+	oidcConfig.ClientSecret = ReplaceStringSecretWithLister(oidcConfig.ClientSecret, a.Secrets, secrets)
+	return oidcConfig
+}
 
-	if !forceResync && mgr.secrets != nil && mgr.configmaps != nil {
+// parseOIDCConfig parses the raw OIDC config without resolving secret references
+func (a *ArgoCDSettings) parseOIDCConfig() *OIDCConfig {
+	if a.OIDCConfigRAW == "" {
 		return nil
 	}
-	if mgr.initContextCancel != nil {
-		mgr.initContextCancel()
+	var oidcConfig OIDCConfig
+	err := yaml.Unmarshal([]byte(a.OIDCConfigRAW), &oidcConfig)
+	if err != nil {
+		log.WithField(logFieldSettingKey, settingsOIDCConfigKey).Warnf("invalid oidc config: %v", err)
+		return nil
 	}
-	ctx, cancel := context.WithCancel(mgr.ctx)
-	mgr.initContextCancel = cancel
-	return mgr.initialize(ctx)
+	return &oidcConfig
 }
 
-func updateSettingsFromConfigMap(settings *ArgoCDSettings, argoCDCM *apiv1.ConfigMap) error {
-	settings.DexConfig = argoCDCM.Data[settingDexConfigKey]
-	settings.OIDCConfigRAW = argoCDCM.Data[settingsOIDCConfigKey]
-	settings.URL = argoCDCM.Data[settingURLKey]
-	repositoriesStr := argoCDCM.Data[repositoriesKey]
-	repositoryCredentialsStr := argoCDCM.Data[repositoryCredentialsKey]
-	var errors []error
-	if repositoriesStr != "" {
-		repositories := make([]RepoCredentials, 0)
-		err := yaml.Unmarshal([]byte(repositoriesStr), &repositories)
-		if err != nil {
-			errors = append(errors, err)
-		} else {
-			settings.Repositories = repositories
-		}
+// RawOIDCConfig returns the oidc.config value exactly as stored in argocd-cm, including unresolved secret references
+func (a *ArgoCDSettings) RawOIDCConfig() string {
+	return a.OIDCConfigRAW
+}
+
+// MissingSecretReferences returns the sorted argocd-secret keys which are referenced as $key or ${key} by the OIDC
+// client secret or by values of the dex config, but which don't exist in the secret. References of the form
+// $secretName:key point to other secrets and are not checked.
+func (a *ArgoCDSettings) MissingSecretReferences() []string {
+	var values []string
+	if oidcConfig := a.parseOIDCConfig(); oidcConfig != nil {
+		values = append(values, oidcConfig.ClientSecret)
 	}
-	if repositoryCredentialsStr != "" {
-		repositoryCredentials := make([]RepoCredentials, 0)
-		err := yaml.Unmarshal([]byte(repositoryCredentialsStr), &repositoryCredentials)
-		if err != nil {
-			errors = append(errors, err)
+	if a.DexConfig != "" {
+		var dexCfg interface{}
+		if err := yaml.Unmarshal([]byte(a.DexConfig), &dexCfg); err != nil {
+			log.WithField(logFieldSettingKey, settingDexConfigKey).Warnf("invalid dex config: %v", err)
 		} else {
-			settings.RepositoryCredentials = repositoryCredentials
+			values = appendStringValues(values, dexCfg)
 		}
 	}
-	helmRepositoriesStr := argoCDCM.Data[helmRepositoriesKey]
-	if helmRepositoriesStr != "" {
-		helmRepositories := make([]HelmRepoCredentials, 0)
-		err := yaml.Unmarshal([]byte(helmRepositoriesStr), &helmRepositories)
-		if err != nil {
-			errors = append(errors, err)
-		} else {
-			settings.HelmRepositories = helmRepositories
+	missing := make(map[string]bool)
+	for _, val := range values {
+		if key, ok := secretReference(val); ok {
+			if _, exists := a.Secrets[key]; !exists {
+				missing[key] = true
+			}
 		}
 	}
-
-	if len(errors) > 0 {
-		return errors[0]
+	keys := make([]string, 0, len(missing))
+	for key := range missing {
+		keys = append(keys, key)
 	}
-	return nil
+	sort.Strings(keys)
+	return keys
 }
 
-// updateSettingsFromSecret transfers settings from a Kubernetes secret into an ArgoCDSettings struct.
-func updateSettingsFromSecret(settings *ArgoCDSettings, argoCDSecret *apiv1.Secret) error {
-	var errs []error
-	adminPasswordHash, ok := argoCDSecret.Data[settingAdminPasswordHashKey]
-	if ok {
-		settings.AdminPasswordHash = string(adminPasswordHash)
-	} else {
-		errs = append(errs, &incompleteSettingsError{message: "admin.password is missing"})
-	}
-	adminPasswordMtimeBytes, ok := argoCDSecret.Data[settingAdminPasswordMtimeKey]
-	if ok {
-		if adminPasswordMtime, err := time.Parse(time.RFC3339, string(adminPasswordMtimeBytes)); err == nil {
-			settings.AdminPasswordMtime = adminPasswordMtime
-		}
-	}
-	secretKey, ok := argoCDSecret.Data[settingServerSignatureKey]
-	if ok {
-		settings.ServerSignature = secretKey
-	} else {
-		errs = append(errs, &incompleteSettingsError{message: "server.secretkey is missing"})
-	}
-	if githubWebhookSecret := argoCDSecret.Data[settingsWebhookGitHubSecretKey]; len(githubWebhookSecret) > 0 {
-		settings.WebhookGitHubSecret = string(githubWebhookSecret)
+// secretReference returns the argocd-secret key referenced by a value of the form $key or ${key}
+func secretReference(val string) (string, bool) {
+	if !strings.HasPrefix(val, "$") {
+		return "", false
 	}
-	if gitlabWebhookSecret := argoCDSecret.Data[settingsWebhookGitLabSecretKey]; len(gitlabWebhookSecret) > 0 {
-		settings.WebhookGitLabSecret = string(gitlabWebhookSecret)
+	key := val[1:]
+	if strings.HasPrefix(key, "{") && strings.HasSuffix(key, "}") {
+		key = key[1 : len(key)-1]
 	}
-	if bitbucketWebhookUUID := argoCDSecret.Data[settingsWebhookBitbucketUUIDKey]; len(bitbucketWebhookUUID) > 0 {
-		settings.WebhookBitbucketUUID = string(bitbucketWebhookUUID)
+	if key == "" || strings.Contains(key, ":") {
+		return "", false
 	}
+	return key, true
+}
 
-	serverCert, certOk := argoCDSecret.Data[settingServerCertificate]
-	serverKey, keyOk := argoCDSecret.Data[settingServerPrivateKey]
-	if certOk && keyOk {
-		cert, err := tls.X509KeyPair(serverCert, serverKey)
-		if err != nil {
-			errs = append(errs, &incompleteSettingsError{message: fmt.Sprintf("invalid x509 key pair %s/%s in secret: %s", settingServerCertificate, settingServerPrivateKey, err)})
-		} else {
-			settings.Certificate = &cert
+// appendStringValues recursively appends the string values of the given unmarshalled YAML object
+func appendStringValues(values []string, obj interface{}) []string {
+	switch val := obj.(type) {
+	case map[string]interface{}:
+		for _, v := range val {
+			values = appendStringValues(values, v)
+		}
+	case []interface{}:
+		for _, v := range val {
+			values = appendStringValues(values, v)
 		}
+	case string:
+		values = append(values, val)
 	}
-	secretValues := make(map[string]string, len(argoCDSecret.Data))
-	for k, v := range argoCDSecret.Data {
-		secretValues[k] = string(v)
+	return values
+}
+
+// OIDCCacheExpiration returns how long OIDC discovery documents may be cached
+func (a *ArgoCDSettings) OIDCCacheExpiration() time.Duration {
+	if a.OIDCCacheExpirationRAW == "" {
+		return defaultOIDCCacheExpiration
 	}
-	settings.Secrets = secretValues
-	if len(errs) > 0 {
-		return errs[0]
+	expiration, err := time.ParseDuration(a.OIDCCacheExpirationRAW)
+	if err != nil || expiration <= 0 {
+		log.WithField(logFieldSettingKey, settingsOIDCCacheExpirationKey).Warnf("invalid %s '%s', using default %v", settingsOIDCCacheExpirationKey, a.OIDCCacheExpirationRAW, defaultOIDCCacheExpiration)
+		return defaultOIDCCacheExpiration
 	}
-	return nil
+	return expiration
 }
 
-// SaveSettings serializes ArgoCDSettings and upserts it into K8s secret/configmap
-func (mgr *SettingsManager) SaveSettings(settings *ArgoCDSettings) error {
-	err := mgr.ensureSynced(false)
+// UICustomStyles returns the URL of a custom stylesheet and custom CSS content for the UI, either may be empty
+func (a *ArgoCDSettings) UICustomStyles() (string, string) {
+	return a.StylesURL, a.StylesContent
+}
+
+// HelmDefaultValueFiles returns the value files applied to all Helm applications
+func (a *ArgoCDSettings) HelmDefaultValueFiles() []string {
+	valueFiles, err := parseStringList(a.HelmDefaultValueFilesRAW)
 	if err != nil {
-		return err
+		log.WithField(logFieldSettingKey, helmDefaultValueFilesKey).Warnf("invalid %s: %v", helmDefaultValueFilesKey, err)
+		return nil
 	}
+	return valueFiles
+}
 
-	// Upsert the config data
-	argoCDCM, err := mgr.configmaps.ConfigMaps(mgr.namespace).Get(common.ArgoCDConfigMapName)
-	createCM := false
+// HelmValueFileSchemes returns the URL schemes Helm value files may use
+func (a *ArgoCDSettings) HelmValueFileSchemes() []string {
+	schemes, err := parseStringList(a.HelmValueFileSchemesRAW)
 	if err != nil {
-		if !apierr.IsNotFound(err) {
-			return err
-		}
-		argoCDCM = &apiv1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: common.ArgoCDConfigMapName,
-			},
-		}
-		createCM = true
-	}
-	if argoCDCM.Data == nil {
-		argoCDCM.Data = make(map[string]string)
-	}
-	if settings.URL != "" {
-		argoCDCM.Data[settingURLKey] = settings.URL
-	} else {
-		delete(argoCDCM.Data, settingURLKey)
-	}
-	if settings.DexConfig != "" {
-		argoCDCM.Data[settingDexConfigKey] = settings.DexConfig
-	} else {
-		delete(argoCDCM.Data, settings.DexConfig)
+		log.WithField(logFieldSettingKey, helmValueFileSchemesKey).Warnf("invalid %s, using defaults %v: %v", helmValueFileSchemesKey, defaultHelmValueFileSchemes, err)
+		return defaultHelmValueFileSchemes
 	}
-	if settings.OIDCConfigRAW != "" {
-		argoCDCM.Data[settingsOIDCConfigKey] = settings.OIDCConfigRAW
-	} else {
-		delete(argoCDCM.Data, settingsOIDCConfigKey)
+	if len(schemes) == 0 {
+		return defaultHelmValueFileSchemes
 	}
-	if len(settings.Repositories) > 0 {
-		yamlStr, err := yaml.Marshal(settings.Repositories)
+	return schemes
+}
+
+// ApplicationNamespaces returns the namespace glob patterns applications are restricted to. An empty list means
+// that all namespaces are allowed.
+func (a *ArgoCDSettings) ApplicationNamespaces() []string {
+	value := strings.TrimSpace(a.ApplicationNamespacesRAW)
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "[") {
+		namespaces, err := parseStringList(value)
 		if err != nil {
-			return err
+			log.WithField(logFieldSettingKey, applicationNamespacesKey).Warnf("invalid %s: %v", applicationNamespacesKey, err)
+			return nil
 		}
-		argoCDCM.Data[repositoriesKey] = string(yamlStr)
-	} else {
-		delete(argoCDCM.Data, repositoriesKey)
+		return namespaces
 	}
-	if len(settings.RepositoryCredentials) > 0 {
-		yamlStr, err := yaml.Marshal(settings.RepositoryCredentials)
-		if err != nil {
-			return err
+	namespaces := make([]string, 0)
+	for _, namespace := range strings.Split(value, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
 		}
-		argoCDCM.Data[repositoryCredentialsKey] = string(yamlStr)
-	} else {
-		delete(argoCDCM.Data, repositoryCredentialsKey)
 	}
-	if len(settings.HelmRepositories) > 0 {
-		yamlStr, err := yaml.Marshal(settings.HelmRepositories)
-		if err != nil {
-			return err
+	return namespaces
+}
+
+// IsNamespaceEnabled returns whether applications may use the given namespace
+func (a *ArgoCDSettings) IsNamespaceEnabled(namespace string) bool {
+	patterns := a.ApplicationNamespaces()
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match(pattern, namespace) {
+			return true
 		}
-		argoCDCM.Data[helmRepositoriesKey] = string(yamlStr)
-	} else {
-		delete(argoCDCM.Data, helmRepositoriesKey)
 	}
+	return false
+}
 
-	if createCM {
-		_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Create(argoCDCM)
-	} else {
-		_, err = mgr.clientset.CoreV1().ConfigMaps(mgr.namespace).Update(argoCDCM)
+// parseStringList parses a YAML list of strings, an empty value yields an empty list
+func parseStringList(value string) ([]string, error) {
+	list := make([]string, 0)
+	if strings.TrimSpace(value) == "" {
+		return list, nil
 	}
+	err := yaml.Unmarshal([]byte(value), &list)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return list, nil
+}
 
-	// Upsert the secret data. Ensure we do not delete any extra keys which user may have added
-	argoCDSecret, err := mgr.secrets.Secrets(mgr.namespace).Get(common.ArgoCDSecretName)
-	createSecret := false
-	if err != nil {
-		if !apierr.IsNotFound(err) {
-			return err
+// AllRepoURLs returns the sorted, de-duplicated URLs of all configured git repositories, repository credentials
+// and helm repositories
+func (a *ArgoCDSettings) AllRepoURLs() []string {
+	urls := make(map[string]bool)
+	for _, repo := range a.Repositories {
+		urls[repo.URL] = true
+	}
+	for _, creds := range a.RepositoryCredentials {
+		urls[creds.URL] = true
+	}
+	for _, repo := range a.HelmRepositories {
+		urls[repo.URL] = true
+	}
+	delete(urls, "")
+	result := make([]string, 0, len(urls))
+	for repoURL := range urls {
+		result = append(result, repoURL)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// scpLikeRepoURLRegex matches scp-like git URLs such as git@github.com:argoproj/argo-cd.git
+var scpLikeRepoURLRegex = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// defaultRepoPorts holds the default ports of the repository URL schemes, which are omitted from normalized URLs
+var defaultRepoPorts = map[string]string{"ssh": "22", "http": "80", "https": "443"}
+
+// NormalizeRepoURL normalizes a repository URL for purposes of credential matching only. SSH, scp-like and HTTPS
+// forms of the same repository, with or without a trailing slash or .git suffix, produce the same value. Only the
+// scheme and host are case-insensitive, and plain HTTP URLs never match the other forms, so credentials of a secure
+// repository aren't sent over an insecure connection.
+func NormalizeRepoURL(repoURL string) string {
+	normalized := strings.TrimSpace(repoURL)
+	if !strings.Contains(normalized, "://") {
+		if matches := scpLikeRepoURLRegex.FindStringSubmatch(normalized); matches != nil {
+			normalized = fmt.Sprintf("ssh://%s/%s", matches[1], strings.TrimPrefix(matches[2], "/"))
 		}
-		argoCDSecret = &apiv1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: common.ArgoCDSecretName,
-			},
-			Data: make(map[string][]byte),
+	}
+	if parsed, err := url.Parse(normalized); err == nil && parsed.Host != "" {
+		scheme := strings.ToLower(parsed.Scheme)
+		host := strings.ToLower(parsed.Hostname())
+		if port := parsed.Port(); port != "" && port != defaultRepoPorts[scheme] {
+			host = host + ":" + port
+		}
+		normalized = host + parsed.Path
+		if scheme == "http" {
+			normalized = "http://" + normalized
+		}
+	}
+	normalized = strings.TrimRight(normalized, "/")
+	normalized = strings.TrimSuffix(normalized, ".git")
+	return strings.TrimRight(normalized, "/")
+}
+
+// GetRepoCredentials returns the repository matching the given URL or, if no repository matches, the repository
+// credentials whose URL is a prefix of the given URL. URLs are compared in their normalized form.
+func (a *ArgoCDSettings) GetRepoCredentials(repoURL string) *RepoCredentials {
+	normalized := NormalizeRepoURL(repoURL)
+	for i := range a.Repositories {
+		if NormalizeRepoURL(a.Repositories[i].URL) == normalized {
+			return &a.Repositories[i]
 		}
-		createSecret = true
 	}
-	if argoCDSecret.Data == nil {
-		argoCDSecret.Data = make(map[string][]byte)
+	for i := range a.RepositoryCredentials {
+		credURL := NormalizeRepoURL(a.RepositoryCredentials[i].URL)
+		if normalized == credURL || strings.HasPrefix(normalized, credURL+"/") {
+			return &a.RepositoryCredentials[i]
+		}
 	}
+	return nil
+}
 
-	argoCDSecret.Data[settingServerSignatureKey] = settings.ServerSignature
-	argoCDSecret.Data[settingAdminPasswordHashKey] = []byte(settings.AdminPasswordHash)
-	argoCDSecret.Data[settingAdminPasswordMtimeKey] = []byte(settings.AdminPasswordMtime.Format(time.RFC3339))
-	if settings.WebhookGitHubSecret != "" {
-		argoCDSecret.Data[settingsWebhookGitHubSecretKey] = []byte(settings.WebhookGitHubSecret)
+// GetHelmRepoCredentials returns the Helm repository matching the given URL or nil if no repository matches. URLs are
+// compared in their normalized form, so trailing slashes and the case of the host are ignored.
+func (a *ArgoCDSettings) GetHelmRepoCredentials(url string) *HelmRepoCredentials {
+	normalized := NormalizeRepoURL(url)
+	for i := range a.HelmRepositories {
+		if NormalizeRepoURL(a.HelmRepositories[i].URL) == normalized {
+			return &a.HelmRepositories[i]
+		}
 	}
-	if settings.WebhookGitLabSecret != "" {
-		argoCDSecret.Data[settingsWebhookGitLabSecretKey] = []byte(settings.WebhookGitLabSecret)
+	return nil
+}
+
+// RepoRequiresSignature returns whether the commits of the repository with the given URL must be signed. Defaults to
+// false for repositories without credentials.
+func (a *ArgoCDSettings) RepoRequiresSignature(url string) bool {
+	repo := a.GetRepoCredentials(url)
+	return repo != nil && repo.RequireSignature
+}
+
+// GetRepoWebhookSecret returns the secret authenticating webhook events of the given repository. Falls back to the
+// global GitHub webhook secret if the repository doesn't have a webhook secret of its own.
+func (mgr *SettingsManager) GetRepoWebhookSecret(repoURL string) (string, error) {
+	settings, err := mgr.GetSettings()
+	if err != nil {
+		return "", err
 	}
-	if settings.WebhookBitbucketUUID != "" {
-		argoCDSecret.Data[settingsWebhookBitbucketUUIDKey] = []byte(settings.WebhookBitbucketUUID)
+	repo := settings.GetRepoCredentials(repoURL)
+	if repo == nil || repo.WebhookSecret == nil {
+		return settings.WebhookGitHubSecret, nil
 	}
-	if settings.Certificate != nil {
-		cert, key := tlsutil.EncodeX509KeyPair(*settings.Certificate)
-		argoCDSecret.Data[settingServerCertificate] = cert
-		argoCDSecret.Data[settingServerPrivateKey] = key
-	} else {
-		delete(argoCDSecret.Data, settingServerCertificate)
-		delete(argoCDSecret.Data, settingServerPrivateKey)
+	secret, err := mgr.secrets.Secrets(mgr.namespace).Get(repo.WebhookSecret.Name)
+	if err != nil {
+		return "", err
 	}
-	if createSecret {
-		_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Create(argoCDSecret)
-	} else {
-		_, err = mgr.clientset.CoreV1().Secrets(mgr.namespace).Update(argoCDSecret)
+	value, ok := secret.Data[repo.WebhookSecret.Key]
+	if !ok {
+		return "", fmt.Errorf("webhook secret of repository '%s' not found: secret '%s' has no key '%s'", repoURL, repo.WebhookSecret.Name, repo.WebhookSecret.Key)
 	}
-	if err != nil {
-		return err
+	return string(value), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
-	return mgr.ResyncInformers()
+	return false
 }
 
-// NewSettingsManager generates a new SettingsManager pointer and returns it
-func NewSettingsManager(ctx context.Context, clientset kubernetes.Interface, namespace string) *SettingsManager {
+// JWTSigningAlgorithm returns the algorithm used to sign JWTs with the server signature. Defaults to HS256.
+func (a *ArgoCDSettings) JWTSigningAlgorithm() string {
+	if a.JWTSigningAlgorithmRAW == "" {
+		return defaultJWTSigningAlgorithm
+	}
+	return a.JWTSigningAlgorithmRAW
+}
 
-	mgr := &SettingsManager{
-		ctx:       ctx,
-		clientset: clientset,
-		namespace: namespace,
-		mutex:     &sync.Mutex{},
+// SecretsDiff returns the sorted keys of the secrets which were added, removed or changed in the other settings
+// compared to these settings. Secret values are not exposed.
+func (a *ArgoCDSettings) SecretsDiff(other *ArgoCDSettings) (added, removed, changed []string) {
+	for k, v := range other.Secrets {
+		if oldValue, ok := a.Secrets[k]; !ok {
+			added = append(added, k)
+		} else if oldValue != v {
+			changed = append(changed, k)
+		}
+	}
+	for k := range a.Secrets {
+		if _, ok := other.Secrets[k]; !ok {
+			removed = append(removed, k)
+		}
 	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
 
-	return mgr
+// SettingChange describes a change of a single setting. Secret-bearing settings don't expose their values, their
+// NewValue is one of SettingAdded, SettingRemoved or SettingChanged and their OldValue is empty.
+type SettingChange struct {
+	Field    string
+	OldValue string
+	NewValue string
 }
 
-func (mgr *SettingsManager) ResyncInformers() error {
-	return mgr.ensureSynced(true)
+// Kinds of changes reported for secret-bearing settings
+const (
+	SettingAdded   = "added"
+	SettingRemoved = "removed"
+	SettingChanged = "changed"
+)
+
+// secretSettingFields are the JSON names of the ArgoCDSettings fields which hold secrets. The dex and OIDC configs
+// are included, since they may hold inline client secrets.
+var secretSettingFields = []string{
+	"dexConfig",
+	"oidcConfig",
+	"adminPasswordHash",
+	"serverSignature",
+	"previousServerSignature",
+	"webhookGitHubSecret",
+	"webhookGitLabSecret",
+	"webhookBitbucketUUID",
+	"webhookBitbucketServerSecret",
 }
 
-// IsSSOConfigured returns whether or not single-sign-on is configured
-func (a *ArgoCDSettings) IsSSOConfigured() bool {
-	if a.IsDexConfigured() {
-		return true
+// DiffSettings returns the changes between the old and new settings sorted by field, e.g. to audit settings updates.
+// Secret-bearing fields, the TLS certificate and the entries of the secrets map are reported without their values.
+func DiffSettings(old, new *ArgoCDSettings) ([]SettingChange, error) {
+	oldFields, err := settingsFields(old)
+	if err != nil {
+		return nil, err
 	}
-	if a.OIDCConfig() != nil {
-		return true
+	newFields, err := settingsFields(new)
+	if err != nil {
+		return nil, err
 	}
-	return false
+	changes := make([]SettingChange, 0)
+	for field := range newFields {
+		if _, ok := oldFields[field]; !ok {
+			oldFields[field] = ""
+		}
+	}
+	for field, oldValue := range oldFields {
+		newValue := newFields[field]
+		if oldValue == newValue {
+			continue
+		}
+		if containsString(secretSettingFields, field) {
+			changes = append(changes, redactedSettingChange(field, oldValue != "", newValue != ""))
+		} else {
+			changes = append(changes, SettingChange{Field: field, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	if !reflect.DeepEqual(old.Certificate, new.Certificate) {
+		changes = append(changes, redactedSettingChange("certificate", old.Certificate != nil, new.Certificate != nil))
+	}
+	added, removed, changed := old.SecretsDiff(new)
+	for _, k := range added {
+		changes = append(changes, redactedSettingChange("secrets."+k, false, true))
+	}
+	for _, k := range removed {
+		changes = append(changes, redactedSettingChange("secrets."+k, true, false))
+	}
+	for _, k := range changed {
+		changes = append(changes, redactedSettingChange("secrets."+k, true, true))
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
 }
 
-func (a *ArgoCDSettings) IsDexConfigured() bool {
-	if a.URL == "" {
-		return false
-	}
-	var dexCfg map[string]interface{}
-	err := yaml.Unmarshal([]byte(a.DexConfig), &dexCfg)
+// settingsFields returns the JSON encoded fields of the settings except the secrets map. String fields are unquoted.
+func settingsFields(settings *ArgoCDSettings) (map[string]string, error) {
+	data, err := json.Marshal(settings)
 	if err != nil {
-		log.Warn("invalid dex yaml config")
-		return false
+		return nil, err
 	}
-	return len(dexCfg) > 0
+	var rawFields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawFields); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	for field, raw := range rawFields {
+		if field == "secrets" {
+			continue
+		}
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			fields[field] = str
+		} else {
+			fields[field] = string(raw)
+		}
+	}
+	return fields, nil
 }
 
-func (a *ArgoCDSettings) OIDCConfig() *OIDCConfig {
-	if a.OIDCConfigRAW == "" {
-		return nil
-	}
-	var oidcConfig OIDCConfig
-	err := yaml.Unmarshal([]byte(a.OIDCConfigRAW), &oidcConfig)
-	if err != nil {
-		log.Warnf("invalid oidc config: %v", err)
-		return nil
+// redactedSettingChange returns a change of a secret-bearing setting which doesn't expose the values
+func redactedSettingChange(field string, hadValue, hasValue bool) SettingChange {
+	change := SettingChange{Field: field, NewValue: SettingChanged}
+	if !hadValue {
+		change.NewValue = SettingAdded
+	} else if !hasValue {
+		change.NewValue = SettingRemoved
 	}
-	oidcConfig.ClientSecret = ReplaceStringSecret(oidcConfig.ClientSecret, a.Secrets)
-	return &oidcConfig
+	return change
 }
 
-// TLSConfig returns a tls.Config with the configured certificates
+// TLSConfig returns a tls.Config with the configured certificates. In insecure mode the server certificate is not
+// served, so it is not trusted and a tls.Config using the system roots is returned.
 func (a *ArgoCDSettings) TLSConfig() *tls.Config {
+	skipVerify := false
+	if oidcConfig := a.OIDCConfig(); oidcConfig != nil && oidcConfig.RootCASkipVerify {
+		log.WithField(logFieldSettingKey, settingsOIDCConfigKey).Warn("TLS certificate verification is DISABLED by rootCASkipVerify. This is insecure and must not be used in production!")
+		skipVerify = true
+	}
+	if a.Insecure {
+		return &tls.Config{InsecureSkipVerify: skipVerify}
+	}
 	if a.Certificate == nil {
+		if skipVerify {
+			return &tls.Config{InsecureSkipVerify: true}
+		}
 		return nil
 	}
 	certPool := x509.NewCertPool()
@@ -683,7 +3522,8 @@ func (a *ArgoCDSettings) TLSConfig() *tls.Config {
 		panic("bad certs")
 	}
 	return &tls.Config{
-		RootCAs: certPool,
+		RootCAs:            certPool,
+		InsecureSkipVerify: skipVerify,
 	}
 }
 
@@ -692,11 +3532,24 @@ func (a *ArgoCDSettings) IssuerURL() string {
 		return oidcConfig.Issuer
 	}
 	if a.DexConfig != "" {
-		return a.URL + common.DexAPIEndpoint
+		return a.baseURL() + common.DexAPIEndpoint
 	}
 	return ""
 }
 
+// TrustedIssuers returns the issuers of the tokens Argo CD trusts: the OIDC issuer and its alias, and the issuer of
+// the bundled dex
+func (a *ArgoCDSettings) TrustedIssuers() []string {
+	var issuers []string
+	if oidcConfig := a.OIDCConfig(); oidcConfig != nil {
+		issuers = append(issuers, oidcConfig.Issuers()...)
+	}
+	if a.IsDexConfigured() {
+		issuers = append(issuers, a.baseURL()+common.DexAPIEndpoint)
+	}
+	return issuers
+}
+
 func (a *ArgoCDSettings) OAuth2ClientID() string {
 	if oidcConfig := a.OIDCConfig(); oidcConfig != nil {
 		return oidcConfig.ClientID
@@ -712,27 +3565,217 @@ func (a *ArgoCDSettings) OAuth2ClientSecret() string {
 		return oidcConfig.ClientSecret
 	}
 	if a.DexConfig != "" {
-		return a.DexOAuth2ClientSecret()
+		secret, err := a.DexOAuth2ClientSecret()
+		if err != nil {
+			log.Warnf("unable to compute dex client secret: %v", err)
+			return ""
+		}
+		return secret
 	}
 	return ""
 }
 
 func (a *ArgoCDSettings) RedirectURL() string {
-	return a.URL + common.CallbackEndpoint
+	return a.baseURL() + common.CallbackEndpoint
+}
+
+// RedirectURIs returns every SSO callback URL Argo CD may use, which need to be registered at the identity provider
+func (a *ArgoCDSettings) RedirectURIs() []string {
+	if !a.IsSSOConfigured() {
+		return nil
+	}
+	redirectURIs := []string{a.RedirectURL(), cliLoopbackRedirectURL}
+	if a.IsDexConfigured() {
+		redirectURIs = append(redirectURIs, a.baseURL()+common.DexAPIEndpoint+"/callback")
+	}
+	return redirectURIs
+}
+
+// ExternalURL returns the configured external URL without a trailing slash. An error is returned unless the URL
+// is an absolute http(s) URL.
+func (a *ArgoCDSettings) ExternalURL() (string, error) {
+	parsed, err := url.Parse(a.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s '%s': %v", settingURLKey, a.URL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid %s '%s': must be an absolute http or https URL", settingURLKey, a.URL)
+	}
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
+
+// BasePath returns the path prefix under which a reverse proxy serves Argo CD, without a trailing slash unless it is
+// the default /. A missing leading slash is added.
+func (a *ArgoCDSettings) BasePath() string {
+	basePath := strings.TrimSpace(a.BasePathRAW)
+	if basePath == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		log.WithField(logFieldSettingKey, settingServerRootPathKey).Warnf("base path '%s' doesn't start with '/', using '/%s'", basePath, basePath)
+		basePath = "/" + basePath
+	}
+	if basePath != "/" {
+		basePath = strings.TrimRight(basePath, "/")
+	}
+	if basePath == "" {
+		return "/"
+	}
+	return basePath
+}
+
+// httpHeaderNameRegex matches header names which are valid tokens according to RFC 7230
+var httpHeaderNameRegex = regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+$")
+
+// parseExtraHTTPHeaders parses the YAML map of extra HTTP response headers and validates the header names
+func parseExtraHTTPHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	if value == "" {
+		return headers, nil
+	}
+	if err := yaml.Unmarshal([]byte(value), &headers); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", settingServerExtraHeadersKey, err)
+	}
+	for name := range headers {
+		if !httpHeaderNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid %s: '%s' is not a valid header name", settingServerExtraHeadersKey, name)
+		}
+	}
+	return headers, nil
+}
+
+// ExtraHTTPHeaders returns the headers added to every HTTP response of the API server, e.g. Content-Security-Policy
+func (a *ArgoCDSettings) ExtraHTTPHeaders() map[string]string {
+	headers, err := parseExtraHTTPHeaders(a.ExtraHTTPHeadersRAW)
+	if err != nil {
+		log.WithField(logFieldSettingKey, settingServerExtraHeadersKey).Warnf("ignoring extra headers: %v", err)
+		return map[string]string{}
+	}
+	return headers
+}
+
+// baseURL returns the normalized external URL including the base path, or the configured URL as is if it is not
+// valid. The base path isn't appended again if the external URL already ends with it.
+func (a *ArgoCDSettings) baseURL() string {
+	externalURL, err := a.ExternalURL()
+	if err != nil {
+		return a.URL
+	}
+	if basePath := a.BasePath(); basePath != "/" && !strings.HasSuffix(externalURL, basePath) {
+		externalURL += basePath
+	}
+	return externalURL
+}
+
+// ServerSignatures returns the signatures which tokens may be verified with, newest first. New tokens must
+// only be signed with the first one. The previous signature is only returned during the grace period following
+// the rotation which replaced it.
+func (a *ArgoCDSettings) ServerSignatures() [][]byte {
+	signatures := [][]byte{a.ServerSignature}
+	if len(a.PreviousServerSignature) > 0 && time.Since(a.ServerSignatureRotationTime) < serverSignatureGracePeriod {
+		signatures = append(signatures, a.PreviousServerSignature)
+	}
+	return signatures
 }
 
 // DexOAuth2ClientSecret calculates an arbitrary, but predictable OAuth2 client secret string derived
 // from the server secret. This is called by the dex startup wrapper (argocd-util rundex), as well
 // as the API server, such that they both independently come to the same conclusion of what the
-// OAuth2 shared client secret should be.
-func (a *ArgoCDSettings) DexOAuth2ClientSecret() string {
-	h := sha256.New()
-	_, err := h.Write(a.ServerSignature)
+// OAuth2 shared client secret should be. The output is stable for a given server signature.
+// ErrNoServerSignature is returned if the server signature has not been initialized.
+func (a *ArgoCDSettings) DexOAuth2ClientSecret() (string, error) {
+	if len(a.ServerSignature) == 0 {
+		return "", ErrNoServerSignature
+	}
+	sha := sha256.Sum256(a.ServerSignature)
+	return base64.URLEncoding.EncodeToString(sha[:])[:40], nil
+}
+
+// ConfiguredWebhookProviders returns the webhook providers whose secret is configured: github, gitlab, bitbucket
+// and/or bitbucketserver
+func (a *ArgoCDSettings) ConfiguredWebhookProviders() []string {
+	providers := make([]string, 0)
+	if a.WebhookGitHubSecret != "" {
+		providers = append(providers, "github")
+	}
+	if a.WebhookGitLabSecret != "" {
+		providers = append(providers, "gitlab")
+	}
+	if a.WebhookBitbucketUUID != "" {
+		providers = append(providers, "bitbucket")
+	}
+	if a.WebhookBitbucketServerSecret != "" {
+		providers = append(providers, "bitbucketserver")
+	}
+	return providers
+}
+
+// webhookSecrets returns the configured webhook secret and the previous secret stored under the given key with the
+// .previous suffix, which is still accepted while the webhook senders are being updated after a rotation
+func (a *ArgoCDSettings) webhookSecrets(secret string, key string) [][]byte {
+	var secrets [][]byte
+	if secret != "" {
+		secrets = append(secrets, []byte(secret))
+	}
+	if previous := a.Secrets[key+webhookPreviousSecretKeySuffix]; previous != "" {
+		secrets = append(secrets, []byte(previous))
+	}
+	return secrets
+}
+
+// verifyHMACSHA256 verifies a signature header of the form sha256=<hex digest> of the payload against the given
+// secrets using a constant-time comparison
+func verifyHMACSHA256(payload []byte, signatureHeader string, secrets [][]byte) bool {
+	const prefix = "sha256="
+	if !strings.HasPrefix(signatureHeader, prefix) {
+		return false
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHeader, prefix))
 	if err != nil {
-		panic(err)
+		return false
+	}
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(payload)
+		if hmac.Equal(signature, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyToken compares the token against the given secrets using a constant-time comparison
+func verifyToken(token string, secrets [][]byte) bool {
+	for _, secret := range secrets {
+		if subtle.ConstantTimeCompare([]byte(token), secret) == 1 {
+			return true
+		}
 	}
-	sha := h.Sum(nil)
-	return base64.URLEncoding.EncodeToString(sha)[:40]
+	return false
+}
+
+// VerifyGitHubWebhook verifies the X-Hub-Signature-256 header of a GitHub webhook event against the GitHub webhook
+// secret, or the previous secret during a rotation. Returns false if no secret is configured.
+func (a *ArgoCDSettings) VerifyGitHubWebhook(payload []byte, signatureHeader string) bool {
+	return verifyHMACSHA256(payload, signatureHeader, a.webhookSecrets(a.WebhookGitHubSecret, settingsWebhookGitHubSecretKey))
+}
+
+// VerifyGitLabWebhook verifies the X-Gitlab-Token header of a GitLab webhook event against the GitLab webhook secret,
+// or the previous secret during a rotation. Returns false if no secret is configured.
+func (a *ArgoCDSettings) VerifyGitLabWebhook(tokenHeader string) bool {
+	return verifyToken(tokenHeader, a.webhookSecrets(a.WebhookGitLabSecret, settingsWebhookGitLabSecretKey))
+}
+
+// VerifyBitbucketWebhook verifies the X-Hook-UUID header of a Bitbucket webhook event against the Bitbucket webhook
+// UUID, or the previous UUID during a rotation. Returns false if no UUID is configured.
+func (a *ArgoCDSettings) VerifyBitbucketWebhook(uuidHeader string) bool {
+	return verifyToken(uuidHeader, a.webhookSecrets(a.WebhookBitbucketUUID, settingsWebhookBitbucketUUIDKey))
+}
+
+// VerifyBitbucketServerWebhook verifies the X-Hub-Signature header of a Bitbucket Server webhook event against the
+// Bitbucket Server webhook secret, or the previous secret during a rotation. Returns false if no secret is configured.
+func (a *ArgoCDSettings) VerifyBitbucketServerWebhook(payload []byte, signatureHeader string) bool {
+	return verifyHMACSHA256(payload, signatureHeader, a.webhookSecrets(a.WebhookBitbucketServerSecret, settingsWebhookBitbucketServerSecretKey))
 }
 
 // Subscribe registers a channel in which to subscribe to settings updates
@@ -740,9 +3783,40 @@ func (mgr *SettingsManager) Subscribe(subCh chan<- *ArgoCDSettings) {
 	mgr.mutex.Lock()
 	defer mgr.mutex.Unlock()
 	mgr.subscribers = append(mgr.subscribers, subCh)
+	mgr.trackSubscriber(subCh)
 	log.Infof("%v subscribed to settings updates", subCh)
 }
 
+// trackSubscriber allocates the channel which is closed when the given channel unsubscribes. Must be called with the
+// mutex held.
+func (mgr *SettingsManager) trackSubscriber(subCh chan<- *ArgoCDSettings) {
+	if mgr.unsubscribed == nil {
+		mgr.unsubscribed = make(map[chan<- *ArgoCDSettings]chan struct{})
+	}
+	if _, ok := mgr.unsubscribed[subCh]; !ok {
+		mgr.unsubscribed[subCh] = make(chan struct{})
+	}
+}
+
+// untrackSubscriber closes the unsubscribed channel of the given channel unless it is still subscribed. Must be called
+// with the mutex held.
+func (mgr *SettingsManager) untrackSubscriber(subCh chan<- *ArgoCDSettings) {
+	for _, ch := range mgr.subscribers {
+		if ch == subCh {
+			return
+		}
+	}
+	for _, sub := range mgr.filteredSubscribers {
+		if sub.ch == subCh {
+			return
+		}
+	}
+	if unsubscribed, ok := mgr.unsubscribed[subCh]; ok {
+		close(unsubscribed)
+		delete(mgr.unsubscribed, subCh)
+	}
+}
+
 // Unsubscribe unregisters a channel from receiving of settings updates
 func (mgr *SettingsManager) Unsubscribe(subCh chan<- *ArgoCDSettings) {
 	mgr.mutex.Lock()
@@ -750,21 +3824,157 @@ func (mgr *SettingsManager) Unsubscribe(subCh chan<- *ArgoCDSettings) {
 	for i, ch := range mgr.subscribers {
 		if ch == subCh {
 			mgr.subscribers = append(mgr.subscribers[:i], mgr.subscribers[i+1:]...)
+			mgr.untrackSubscriber(subCh)
+			log.Infof("%v unsubscribed from settings updates", subCh)
+			return
+		}
+	}
+	for i, sub := range mgr.filteredSubscribers {
+		if sub.ch == subCh {
+			mgr.filteredSubscribers = append(mgr.filteredSubscribers[:i], mgr.filteredSubscribers[i+1:]...)
+			mgr.untrackSubscriber(subCh)
 			log.Infof("%v unsubscribed from settings updates", subCh)
 			return
 		}
 	}
 }
 
-func (mgr *SettingsManager) notifySubscribers(newSettings *ArgoCDSettings) {
+// SubscribeBuffered returns a channel with the given buffer size which receives settings updates. When the buffer is
+// full the oldest queued settings are dropped, so the settings manager never blocks on slow subscribers.
+func (mgr *SettingsManager) SubscribeBuffered(size int) <-chan *ArgoCDSettings {
+	if size < 1 {
+		size = 1
+	}
+	subCh := make(chan *ArgoCDSettings, size)
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.bufferedSubscribers = append(mgr.bufferedSubscribers, subCh)
+	log.Infof("%v subscribed to buffered settings updates", subCh)
+	return subCh
+}
+
+// UnsubscribeBuffered unregisters a channel returned by SubscribeBuffered
+func (mgr *SettingsManager) UnsubscribeBuffered(subCh <-chan *ArgoCDSettings) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	for i, ch := range mgr.bufferedSubscribers {
+		if ch == subCh {
+			mgr.bufferedSubscribers = append(mgr.bufferedSubscribers[:i], mgr.bufferedSubscribers[i+1:]...)
+			log.Infof("%v unsubscribed from buffered settings updates", subCh)
+			return
+		}
+	}
+}
+
+// WatchSettings returns a channel which first receives the current settings and then settings updates. The
+// channel is unsubscribed and closed when the given context is cancelled.
+func (mgr *SettingsManager) WatchSettings(ctx context.Context) <-chan *ArgoCDSettings {
+	updateCh := mgr.SubscribeBuffered(1)
+	watchCh := make(chan *ArgoCDSettings)
+	go func() {
+		defer close(watchCh)
+		defer mgr.UnsubscribeBuffered(updateCh)
+		var prevSettings *ArgoCDSettings
+		send := func(newSettings *ArgoCDSettings) bool {
+			if reflect.DeepEqual(prevSettings, newSettings) {
+				return true
+			}
+			select {
+			case watchCh <- newSettings:
+				prevSettings = newSettings
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if currentSettings, err := mgr.GetSettings(); err != nil {
+			log.Warnf("Unable to get current settings: %v", err)
+		} else if !send(currentSettings) {
+			return
+		}
+		for {
+			select {
+			case newSettings := <-updateCh:
+				if !send(newSettings) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return watchCh
+}
+
+// sendDropOldest sends the settings to the given channel, dropping the oldest queued settings if the channel is full
+func sendDropOldest(subCh chan *ArgoCDSettings, newSettings *ArgoCDSettings) {
+	for {
+		select {
+		case subCh <- newSettings:
+			return
+		default:
+		}
+		select {
+		case <-subCh:
+		default:
+		}
+	}
+}
+
+// SubscribeFiltered registers a channel which is notified only when one of the given settings sections
+// (url, admin, dex, oidc, signature, tls, webhook, secrets, repositories) has changed
+func (mgr *SettingsManager) SubscribeFiltered(sections []string, subCh chan<- *ArgoCDSettings) {
 	mgr.mutex.Lock()
 	defer mgr.mutex.Unlock()
-	if len(mgr.subscribers) > 0 {
-		log.Infof("Notifying %d settings subscribers: %v", len(mgr.subscribers), mgr.subscribers)
-		for _, sub := range mgr.subscribers {
-			sub <- newSettings
+	for _, section := range sections {
+		if _, ok := settingsSections[section]; !ok {
+			log.Warnf("unknown settings section '%s' ignored", section)
+		}
+	}
+	mgr.filteredSubscribers = append(mgr.filteredSubscribers, filteredSubscriber{sections: sections, ch: subCh})
+	mgr.trackSubscriber(subCh)
+	log.Infof("%v subscribed to settings updates of %v", subCh, sections)
+}
+
+// notifySubscribers sends the new settings to a snapshot of the subscribers taken under the lock. The settings are sent
+// outside of the lock, so subscribers may subscribe and unsubscribe while handling a notification. A pending send is
+// abandoned as soon as its subscriber unsubscribes, so subscribers which stop reading can't block the notifier.
+func (mgr *SettingsManager) notifySubscribers(newSettings *ArgoCDSettings) {
+	mgr.mutex.Lock()
+	subscribers := append([]chan<- *ArgoCDSettings(nil), mgr.subscribers...)
+	filteredSubscribers := append([]filteredSubscriber(nil), mgr.filteredSubscribers...)
+	bufferedSubscribers := append([]chan *ArgoCDSettings(nil), mgr.bufferedSubscribers...)
+	unsubscribed := make(map[chan<- *ArgoCDSettings]chan struct{}, len(mgr.unsubscribed))
+	for subCh, ch := range mgr.unsubscribed {
+		unsubscribed[subCh] = ch
+	}
+	lastNotified := mgr.lastNotified
+	mgr.lastNotified = newSettings
+	mgr.mutex.Unlock()
+
+	if len(subscribers) > 0 {
+		log.Infof("Notifying %d settings subscribers: %v", len(subscribers), subscribers)
+		for _, sub := range subscribers {
+			sendUntilUnsubscribed(sub, unsubscribed[sub], newSettings)
 		}
 	}
+	for _, sub := range filteredSubscribers {
+		if sectionsChanged(lastNotified, newSettings, sub.sections) {
+			sendUntilUnsubscribed(sub.ch, unsubscribed[sub.ch], newSettings)
+		}
+	}
+	for _, subCh := range bufferedSubscribers {
+		sendDropOldest(subCh, newSettings)
+	}
+}
+
+// sendUntilUnsubscribed sends the settings to the given channel unless the unsubscribed channel is closed first
+func sendUntilUnsubscribed(subCh chan<- *ArgoCDSettings, unsubscribed <-chan struct{}, newSettings *ArgoCDSettings) {
+	select {
+	case subCh <- newSettings:
+	case <-unsubscribed:
+		log.Infof("%v unsubscribed before receiving settings update", subCh)
+	}
 }
 
 func isIncompleteSettingsError(err error) bool {
@@ -790,7 +4000,7 @@ func (mgr *SettingsManager) InitializeSettings(insecureModeEnabled bool) (*ArgoC
 		cdSettings.ServerSignature = signature
 		log.Info("Initialized server signature")
 	}
-	if cdSettings.AdminPasswordHash == "" {
+	if cdSettings.AdminPasswordHash == "" && cdSettings.IsAdminEnabled() {
 		defaultPassword, err := os.Hostname()
 		if err != nil {
 			return nil, err
@@ -807,6 +4017,7 @@ func (mgr *SettingsManager) InitializeSettings(insecureModeEnabled bool) (*ArgoC
 		cdSettings.AdminPasswordMtime = time.Now().UTC()
 		log.Info("Initialized admin mtime")
 	}
+	cdSettings.Insecure = insecureModeEnabled
 
 	if cdSettings.Certificate == nil && !insecureModeEnabled {
 		// generate TLS cert
@@ -854,8 +4065,34 @@ func ReplaceStringSecret(val string, secretValues map[string]string) string {
 	secretKey := val[1:]
 	secretVal, ok := secretValues[secretKey]
 	if !ok {
-		log.Warnf("config referenced '%s', but key does not exist in secret", val)
+		log.WithField(logFieldSettingKey, secretKey).Warnf("config referenced '%s', but key does not exist in secret", val)
 		return val
 	}
 	return secretVal
 }
+
+// ReplaceStringSecretWithLister works like ReplaceStringSecret, but additionally resolves references of the form
+// $secretName:key against the secrets of the given lister. Such references are returned unchanged if the lister is nil.
+func ReplaceStringSecretWithLister(val string, secretValues map[string]string, secrets v1listers.SecretNamespaceLister) string {
+	if val == "" || !strings.HasPrefix(val, "$") {
+		return val
+	}
+	parts := strings.SplitN(val[1:], ":", 2)
+	if len(parts) != 2 {
+		return ReplaceStringSecret(val, secretValues)
+	}
+	if secrets == nil {
+		return val
+	}
+	secret, err := secrets.Get(parts[0])
+	if err != nil {
+		log.WithFields(log.Fields{logFieldSettingKey: parts[1], logFieldSecret: parts[0]}).Warnf("config referenced '%s', but secret '%s' cannot be retrieved: %v", val, parts[0], err)
+		return val
+	}
+	secretVal, ok := secret.Data[parts[1]]
+	if !ok {
+		log.WithFields(log.Fields{logFieldSettingKey: parts[1], logFieldSecret: parts[0]}).Warnf("config referenced '%s', but key does not exist in secret '%s'", val, parts[0])
+		return val
+	}
+	return string(secretVal)
+}